@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/extscorer"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/history"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer"
+	scorerconfig "github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+type backtestFlags struct {
+	historyDir string
+	from       string
+	to         string
+	output     string
+	hintsFile  string
+
+	scorerConfig   scorerconfig.ScorerConfiguration
+	externalScorer extscorer.Config
+}
+
+func parseBacktestFlags(args []string) (*backtestFlags, error) {
+	flags := &backtestFlags{}
+	fs := flag.NewFlagSet("backtest", flag.ExitOnError)
+	scorerconfig.BindFlags(&flags.scorerConfig, fs)
+	fs.StringVar(&flags.historyDir, "history-dir", "", "Directory with the recorded snapshots (see --record-history-dir)")
+	fs.StringVar(&flags.from, "from", "", "Start of the period (RFC3339), default is 7 days before the end")
+	fs.StringVar(&flags.to, "to", "", "End of the period (RFC3339), default is now")
+	fs.StringVar(&flags.output, "output", "text", "Output format: text or json")
+	fs.StringVar(&flags.hintsFile, "hints-file", "", "YAML file with bonus, malus and priorities hints, like the hints ConfigMap")
+	fs.StringVar(&flags.externalScorer.URL, "external-scorer-url", "", "URL of the external scorer to replay the vetoes, bonus and malus, empty to disable it")
+	fs.DurationVar(&flags.externalScorer.Timeout, "external-scorer-timeout", 2*time.Second, "")
+	fs.StringToStringVar(&flags.externalScorer.Headers, "external-scorer-headers", nil, "Headers of the requests to the external scorer")
+	fs.Parse(args)
+	scorerconfig.ApplyDialFlags(&flags.scorerConfig, fs)
+	if err := scorerconfig.Validate(flags.scorerConfig); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+func parsePeriod(from, to string) (time.Time, time.Time, error) {
+	var err error
+	end := time.Now()
+	if to != "" {
+		if end, err = time.Parse(time.RFC3339, to); err != nil {
+			return end, end, err
+		}
+	}
+	start := end.Add(-7 * 24 * time.Hour)
+	if from != "" {
+		if start, err = time.Parse(time.RFC3339, from); err != nil {
+			return start, end, err
+		}
+	}
+	if !start.Before(end) {
+		return start, end, fmt.Errorf("the start of the period has to be before the end")
+	}
+	return start, end, nil
+}
+
+func loadHintsFile(path string) (scorer.HintsSpec, error) {
+	hints := scorer.HintsSpec{}
+	if path == "" {
+		return hints, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return hints, err
+	}
+	err = yaml.Unmarshal(data, &hints)
+	return hints, err
+}
+
+func runBacktest(args []string) error {
+	flags, err := parseBacktestFlags(args)
+	if err != nil {
+		return err
+	}
+	if flags.historyDir == "" {
+		return fmt.Errorf("--history-dir is mandatory")
+	}
+	from, to, err := parsePeriod(flags.from, flags.to)
+	if err != nil {
+		return err
+	}
+	hints, err := loadHintsFile(flags.hintsFile)
+	if err != nil {
+		return err
+	}
+	store, err := history.NewStore(flags.historyDir)
+	if err != nil {
+		return err
+	}
+	snaps, err := store.Load(from, to)
+	if err != nil {
+		return err
+	}
+	var ext *extscorer.Client
+	if flags.externalScorer.URL != "" {
+		if ext, err = extscorer.NewClient(flags.externalScorer); err != nil {
+			return err
+		}
+	}
+	report, err := scorer.Backtest(snaps, to, flags.scorerConfig, hints, ext)
+	if err != nil {
+		return err
+	}
+
+	switch flags.output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "text":
+		printBacktestReport(report)
+		return nil
+	default:
+		return fmt.Errorf("unknown output format: %s", flags.output)
+	}
+}
+
+func printBacktestReport(report *scorer.BacktestReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tTOP PRIORITY\tPRICE\tHOURS\tTOP ASGS")
+	for _, step := range report.Steps {
+		fmt.Fprintf(w, "%s\t%d\t%.4f\t%.2f\t%s\n", step.Time.Format(time.RFC3339),
+			step.TopPriority, step.TopPrice, step.Hours, strings.Join(step.TopASGs, ","))
+	}
+	w.Flush()
+
+	fmt.Printf("\nPeriod: %s - %s\n", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339))
+	fmt.Printf("Snapshots: %d\n", len(report.Steps))
+	fmt.Printf("Top choice changes: %d\n", report.TopChanges)
+	fmt.Printf("Estimated cost (single instance): %.4f\n", report.EstimatedCost)
+	fmt.Printf("The cost is the price of one instance of the top ASGs times the hours, the scoring profiles are not replayed\n\n")
+
+	asgs := []string{}
+	for asg := range report.CostByASG {
+		asgs = append(asgs, asg)
+	}
+	sort.Strings(asgs)
+	w = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ASG\tESTIMATED COST")
+	for _, asg := range asgs {
+		fmt.Fprintf(w, "%s\t%.4f\n", asg, report.CostByASG[asg])
+	}
+	w.Flush()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/nodes"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/utils"
+)
+
+// cluster keeps the clients of a kubeconfig context, the nodes distribution and
+// the events recorder are shared by all the targets of the same cluster.
+type cluster struct {
+	clientset         clientset.Interface
+	dynamic           dynamic.Interface
+	nodesDistribution *nodes.NodesDistribution
+	recorder          record.EventRecorder
+}
+
+type clusters struct {
+	flags     *Flags
+	byContext map[string]*cluster
+}
+
+func newClusters(flags *Flags) *clusters {
+	return &clusters{flags: flags, byContext: make(map[string]*cluster)}
+}
+
+// get returns the cluster of the kubeconfig context, the empty one is the context
+// selected by the flags (or the in-cluster configuration).
+func (c *clusters) get(context string) (*cluster, error) {
+	if cl, ok := c.byContext[context]; ok {
+		return cl, nil
+	}
+	overrides := *c.flags.overrides
+	if context != "" {
+		overrides.CurrentContext = context
+	}
+	config, err := utils.GetRestConfig(c.flags.kubeconfig, &overrides)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	nd, err := nodes.NewNodesDistribution(cs)
+	if err != nil {
+		return nil, err
+	}
+	cl := &cluster{clientset: cs, dynamic: dc, nodesDistribution: nd}
+	if c.flags.events {
+		cl.recorder = scorer.NewEventRecorder(cs)
+	}
+	c.byContext[context] = cl
+	return cl, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/configfile"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/metrics"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/profiles"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer"
+	scorerconfig "github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+// flags that can't be set by the configuration file
+var notConfigurableFlags = map[string]bool{"config": true, "version": true}
+
+// configWatcher reloads the configuration file (and the files it references) when it changes and
+// applies the scorer configurations, the outputs and the refresh interval to the running scorers,
+// the other settings need a restart.
+type configWatcher struct {
+	flags   *Flags
+	targets []scorerconfig.Target
+	scorers []*scorer.Scorer
+	// last are the settings of the last reload, the outputs and the refresh interval are
+	// applied only when the files change them (they can be changed by a PriorityHelperConfig too)
+	lastFlags   *Flags
+	lastTargets []scorerconfig.Target
+	checksums   map[string]string
+	// profiles are the scheduled profiles, loaded at start, they have to be valid with the reloaded configurations
+	profiles *profiles.Config
+}
+
+func newConfigWatcher(flags *Flags, targets []scorerconfig.Target, scorers []*scorer.Scorer, profilesConfig *profiles.Config) *configWatcher {
+	w := &configWatcher{flags: flags, targets: targets, scorers: scorers, lastFlags: flags, lastTargets: targets, profiles: profilesConfig}
+	w.checksums, _ = w.filesChecksums()
+	return w
+}
+
+// watchedFiles returns the configuration file and the files whose path is a flag
+func (f *Flags) watchedFiles() []string {
+	files := []string{}
+	for _, path := range []string{f.configFile, f.targetsConfig, f.poolsConfig, f.profilesConfig, f.webhooksConfig} {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// filesChecksums returns the checksum of every watched file
+func (w *configWatcher) filesChecksums() (map[string]string, error) {
+	return configfile.Checksums(w.lastFlags.watchedFiles())
+}
+
+func (w *configWatcher) Start(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(w.flags.configReloadInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				w.check()
+			}
+		}
+	}()
+}
+
+func (w *configWatcher) check() {
+	checksums, err := w.filesChecksums()
+	if err != nil {
+		klog.Errorf("Error reading configuration file: %v", err)
+		return
+	}
+	if reflect.DeepEqual(checksums, w.checksums) {
+		return
+	}
+	changed := make(map[string]bool)
+	for path, checksum := range checksums {
+		changed[path] = w.checksums[path] != checksum
+	}
+	// an invalid file is reported once, until it changes again
+	w.checksums = checksums
+	if err := w.reload(changed); err != nil {
+		klog.Errorf("Error reloading configuration file, keeping the current configuration: %v", err)
+		metrics.IncConfigReloads("error")
+		return
+	}
+	metrics.IncConfigReloads("applied")
+	// the reload can reference other files
+	if checksums, err := w.filesChecksums(); err == nil {
+		w.checksums = checksums
+	}
+}
+
+func (w *configWatcher) reload(changed map[string]bool) error {
+	// a fresh flag set, so that an invalid file doesn't change the current configuration
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	flags, err := loadFlags(fs, os.Args[1:])
+	if err != nil {
+		return err
+	}
+	targets, err := buildTargets(flags)
+	if err != nil {
+		return err
+	}
+	if len(targets) != len(w.targets) {
+		return fmt.Errorf("the targets changed, a restart is needed")
+	}
+	for i, target := range targets {
+		if target.Name != w.targets[i].Name {
+			return fmt.Errorf("the targets changed, a restart is needed")
+		}
+	}
+	if flags.scorerRefreshInterval <= 0 {
+		return fmt.Errorf("scorer-refresh-interval has to be positive")
+	}
+
+	// the profiles in use and the ones of the file, applied at restart, have to be valid with the new configuration
+	if w.profiles != nil {
+		for _, target := range targets {
+			if err := w.profiles.ValidateFor(target.Scorer); err != nil {
+				return fmt.Errorf("target %s: %v", target.Name, err)
+			}
+		}
+	}
+	if flags.profilesConfig != "" {
+		if _, err := loadProfiles(flags.profilesConfig, targets); err != nil {
+			return err
+		}
+	}
+
+	if requiresRestart(w.flags, flags, w.targets, targets) || changed[flags.profilesConfig] || changed[flags.webhooksConfig] {
+		klog.Warningf("Configuration file %s changed settings that are applied only at restart", w.flags.configFile)
+	}
+	for i, target := range targets {
+		s, last := w.scorers[i], w.lastTargets[i]
+		s.SetConfig(target.Scorer)
+		if target.OutputConfigMapName != last.OutputConfigMapName || target.BreakdownConfigMapName != last.BreakdownConfigMapName {
+			if err := s.SetOutputConfigMaps(target.OutputConfigMapName, target.BreakdownConfigMapName); err != nil {
+				klog.Errorf("Error changing the output ConfigMaps of target %s: %v", target.Name, err)
+			}
+		}
+		if flags.scorerRefreshInterval != w.lastFlags.scorerRefreshInterval {
+			if err := s.SetRefreshInterval(flags.scorerRefreshInterval); err != nil {
+				klog.Errorf("Error changing the refresh interval of target %s: %v", target.Name, err)
+			}
+		}
+	}
+	w.lastFlags, w.lastTargets = flags, targets
+	klog.Infof("Configuration file %s reloaded", w.flags.configFile)
+	return nil
+}
+
+// requiresRestart tells whether something other than the settings applied live (scorer
+// configurations, pools, outputs and refresh interval) changed
+func requiresRestart(oldFlags, newFlags *Flags, oldTargets, newTargets []scorerconfig.Target) bool {
+	o, n := *oldFlags, *newFlags
+	for _, f := range []*Flags{&o, &n} {
+		f.scorerConfig, f.poolsConfig = scorerconfig.ScorerConfiguration{}, ""
+		f.outConfigMapName, f.breakdownConfigMapName = "", ""
+		f.scorerRefreshInterval = 0
+	}
+	if !reflect.DeepEqual(o, n) {
+		return true
+	}
+	for i := range oldTargets {
+		o, n := oldTargets[i], newTargets[i]
+		for _, t := range []*scorerconfig.Target{&o, &n} {
+			t.Scorer = scorerconfig.ScorerConfiguration{}
+			t.OutputConfigMapName, t.BreakdownConfigMapName = "", ""
+		}
+		if !reflect.DeepEqual(o, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadProfiles reads the profiles file and checks every profile with the scorer configuration of every target
+func loadProfiles(path string, targets []scorerconfig.Target) (*profiles.Config, error) {
+	profilesConfig, err := profiles.LoadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, target := range targets {
+		if err := profilesConfig.ValidateFor(target.Scorer); err != nil {
+			return nil, fmt.Errorf("%s: target %s: %v", path, target.Name, err)
+		}
+	}
+	return profilesConfig, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	flag "github.com/spf13/pflag"
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/extscorer"
+)
+
+type externalScorerStubFlags struct {
+	address string
+	rules   string
+}
+
+func parseExternalScorerStubFlags(args []string) *externalScorerStubFlags {
+	flags := &externalScorerStubFlags{}
+	fs := flag.NewFlagSet("external-scorer-stub", flag.ExitOnError)
+	fs.StringVar(&flags.address, "http-address", "127.0.0.1:8090", "Address to listen on, only the loopback by default")
+	fs.StringVar(&flags.rules, "rules", "", "YAML file with the decisions (bonus, malus, veto, reason) for the ASGs matching a pattern")
+	fs.Parse(args)
+	return flags
+}
+
+// runExternalScorerStub serves a stand-in external scorer answering with static decisions
+func runExternalScorerStub(args []string) error {
+	flags := parseExternalScorerStubFlags(args)
+	if flags.rules == "" {
+		return fmt.Errorf("--rules is mandatory")
+	}
+	rules, err := extscorer.LoadStubRules(flags.rules)
+	if err != nil {
+		return err
+	}
+	klog.Infof("External scorer stub listening on %s with %d rules", flags.address, len(rules))
+	return http.ListenAndServe(flags.address, extscorer.StubHandler(rules))
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -15,16 +16,34 @@ import (
 	"k8s.io/klog"
 	"k8s.io/kubernetes/pkg/client/leaderelectionconfig"
 
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/configfile"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/extscorer"
 	scorerconfig "github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
 )
 
 type Flags struct {
 	version bool
 
+	configFile           string
+	configReloadInterval time.Duration
+
 	kubeconfig             string
 	autoDiscoverASGsByTags string
 	overrides              *clientcmd.ConfigOverrides
+	namespace              string
+	targetsConfig          string
+	contexts               []string
 	outConfigMapName       string
+	breakdownConfigMapName string
+	httpAddress            string
+	recordHistoryDir       string
+	events                 bool
+	webhooksConfig         string
+	cloudWatchNamespace    string
+	configResources        bool
+	profilesConfig         string
+	poolsConfig            string
+	externalScorer         extscorer.Config
 
 	leaderElection componentbaseconfig.LeaderElectionConfiguration
 
@@ -33,17 +52,62 @@ type Flags struct {
 	asgDiscovererRefreshInterval time.Duration
 	pricerRefreshInterval        time.Duration
 	scorerRefreshInterval        time.Duration
+	recordHistoryInterval        time.Duration
+	recordHistoryRetention       time.Duration
+	recordHistoryMaxSizeMB       int64
+	cloudWatchInterval           time.Duration
+	lintInterval                 time.Duration
 }
 
 func parseFlags() *Flags {
-	flags := &Flags{}
 	klog.InitFlags(nil)
+	flags, err := loadFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		panic(err.Error())
+	}
+	return flags
+}
+
+// loadFlags parses the arguments and then applies the settings of the --config file,
+// the flags given as arguments take precedence over the file.
+func loadFlags(fs *flag.FlagSet, args []string) (*Flags, error) {
+	flags := bindFlags(fs)
+	fs.AddGoFlagSet(goflag.CommandLine)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.configFile != "" {
+		settings, err := configfile.Read(flags.configFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := configfile.Apply(fs, settings, notConfigurableFlags); err != nil {
+			return nil, fmt.Errorf("%s: %v", flags.configFile, err)
+		}
+	}
+	if f := fs.Lookup("pools-config"); f != nil {
+		flags.poolsConfig = f.Value.String()
+	}
+	scorerconfig.ApplyDialFlags(&flags.scorerConfig, fs)
+	// an invalid scorer configuration fails at startup, whatever its source
+	if err := scorerconfig.Validate(flags.scorerConfig); err != nil {
+		if flags.configFile != "" {
+			return nil, fmt.Errorf("%s: %v", flags.configFile, err)
+		}
+		return nil, err
+	}
+	return flags, nil
+}
+
+func bindFlags(fs *flag.FlagSet) *Flags {
+	flags := &Flags{}
 	flags.overrides = &clientcmd.ConfigOverrides{}
 	clientcmd.BindOverrideFlags(
-		flags.overrides, flag.CommandLine,
+		flags.overrides, fs,
 		clientcmd.ConfigOverrideFlags{
 			CurrentContext: clientcmd.FlagInfo{
-				clientcmd.FlagContext, "", "", "The name of the kubeconfig context to use",
+				LongName:    clientcmd.FlagContext,
+				Description: "The name of the kubeconfig context to use",
 			},
 		})
 
@@ -54,24 +118,51 @@ func parseFlags() *Flags {
 		RetryPeriod:   metav1.Duration{Duration: 5 * time.Second},
 		ResourceLock:  resourcelock.EndpointsResourceLock,
 	}
-	leaderelectionconfig.BindFlags(&flags.leaderElection, flag.CommandLine)
+	leaderelectionconfig.BindFlags(&flags.leaderElection, fs)
 
 	flags.scorerConfig = scorerconfig.ScorerConfiguration{}
-	scorerconfig.BindFlags(&flags.scorerConfig, flag.CommandLine)
+	scorerconfig.BindFlags(&flags.scorerConfig, fs)
+
+	fs.BoolVar(&flags.version, "version", false, "Print version and exit")
+	fs.StringVar(&flags.configFile, "config", "", "YAML configuration file with the flags settings, reloaded when it changes")
+	fs.BoolVar(&flags.configResources, "config-resources", false, "Apply the PriorityHelperConfig resource named as the target, in its namespace, and report its status")
+	fs.DurationVar(&flags.configReloadInterval, "config-reload-interval", 30*time.Second, "Interval of the configuration file check, 0 to disable the reload")
+
+	fs.StringVar(&flags.kubeconfig, clientcmd.RecommendedConfigPathFlag, "", "kubeconfig path")
+	fs.StringVar(&flags.autoDiscoverASGsByTags, "auto-discover-asg-by-tags", "", "")
+	fs.StringVar(&flags.namespace, "namespace", systemNamespace, "Namespace of the output and hints ConfigMaps and of the leader election lease")
+	fs.StringVar(&flags.targetsConfig, "targets-config", "", "YAML file with the targets, every one with its own ASG tags, namespace, ConfigMaps and scorer configuration")
+	fs.StringSliceVar(&flags.contexts, "contexts", nil, "Kubeconfig contexts of the clusters to maintain, a target with the default configuration for each of them")
+	fs.StringVar(&flags.outConfigMapName, "output-configmap", priorityConfigMapName, "")
+	fs.StringVar(&flags.breakdownConfigMapName, "breakdown-configmap", "", "ConfigMap to publish the scores breakdown (used by the kubectl plugin), empty to disable it")
+	fs.StringVar(&flags.httpAddress, "http-address", "127.0.0.1:8080", "Address of the HTTP server (what-if API, metrics, health and debug endpoints), they are not authenticated so only the loopback by default, empty to disable it")
+
+	fs.DurationVar(&flags.spotAdvisorRefreshInterval, "spot-advisor-refresh-interval", 600*time.Second, "")
+	fs.DurationVar(&flags.asgDiscovererRefreshInterval, "asg-discoverer-refresh-interval", 600*time.Second, "")
+	fs.DurationVar(&flags.pricerRefreshInterval, "pricer-refresh-interval", 600*time.Second, "")
+	fs.DurationVar(&flags.scorerRefreshInterval, "scorer-refresh-interval", 600*time.Second, "")
+
+	fs.BoolVar(&flags.events, "events", true, "Emit Kubernetes events about priority changes and data problems")
+
+	fs.StringVar(&flags.profilesConfig, "profiles-config", "", "YAML file with the scorer configuration profiles and the schedule activating them")
+
+	fs.StringVar(&flags.webhooksConfig, "webhooks-config", "", "YAML file with the webhooks to notify about priorities changes")
+
+	fs.StringVar(&flags.externalScorer.URL, "external-scorer-url", "", "URL of the external scorer receiving the scored ASGs and answering with bonus, malus and vetoes, empty to disable it")
+	fs.DurationVar(&flags.externalScorer.Timeout, "external-scorer-timeout", 2*time.Second, "")
+	fs.StringVar(&flags.externalScorer.FailurePolicy, "external-scorer-failure-policy", extscorer.FailOpen, "When the external scorer fails: open (publish the built-in scores) or closed (don't update the priorities)")
+	fs.StringToStringVar(&flags.externalScorer.Headers, "external-scorer-headers", nil, "Headers of the requests to the external scorer")
 
-	flag.BoolVar(&flags.version, "version", false, "Print version and exit")
+	fs.StringVar(&flags.cloudWatchNamespace, "cloudwatch-namespace", "", "CloudWatch namespace to publish priorities, scores and prices, empty to disable it")
+	fs.DurationVar(&flags.cloudWatchInterval, "cloudwatch-interval", 60*time.Second, "")
 
-	flag.StringVar(&flags.kubeconfig, clientcmd.RecommendedConfigPathFlag, "", "kubeconfig path")
-	flag.StringVar(&flags.autoDiscoverASGsByTags, "auto-discover-asg-by-tags", "", "")
-	flag.StringVar(&flags.outConfigMapName, "output-configmap", priorityConfigMapName, "")
+	fs.DurationVar(&flags.lintInterval, "lint-interval", 3600*time.Second, "Interval of the ASG conventions check, 0 to disable it")
 
-	flag.DurationVar(&flags.spotAdvisorRefreshInterval, "spot-advisor-refresh-interval", 600*time.Second, "")
-	flag.DurationVar(&flags.asgDiscovererRefreshInterval, "asg-discoverer-refresh-interval", 600*time.Second, "")
-	flag.DurationVar(&flags.pricerRefreshInterval, "pricer-refresh-interval", 600*time.Second, "")
-	flag.DurationVar(&flags.scorerRefreshInterval, "scorer-refresh-interval", 600*time.Second, "")
+	fs.StringVar(&flags.recordHistoryDir, "record-history-dir", "", "Directory to record the data snapshots for the backtest, empty to disable it")
+	fs.DurationVar(&flags.recordHistoryInterval, "record-history-interval", 600*time.Second, "")
+	fs.DurationVar(&flags.recordHistoryRetention, "record-history-retention", 30*24*time.Hour, "Age of the recorded snapshots to keep, 0 to keep them all")
+	fs.Int64Var(&flags.recordHistoryMaxSizeMB, "record-history-max-size-mb", 1024, "Size of the recorded snapshots to keep, the oldest days are removed first, 0 for no limit")
 
-	flag.CommandLine.AddGoFlagSet(goflag.CommandLine)
-	flag.Parse()
 	return flags
 }
 
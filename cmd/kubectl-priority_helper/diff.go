@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer"
+)
+
+func prioritiesByName(priorities map[int][]string) map[string]int {
+	res := make(map[string]int)
+	for prio, names := range priorities {
+		for _, name := range names {
+			res[name] = prio
+		}
+	}
+	return res
+}
+
+func loadHintsFile(path string) (scorer.HintsSpec, error) {
+	hints := scorer.HintsSpec{}
+	if path == "" {
+		return hints, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return hints, err
+	}
+	err = yaml.Unmarshal(data, &hints)
+	return hints, err
+}
+
+// dryRun asks the helper, through the API server service proxy, to compute the priorities
+// with the current data optionally changing the configuration and adding hints.
+func (p *plugin) dryRun() (*scorer.Result, error) {
+	req := scorer.WhatIfRequest{}
+	if p.flags.configJSON != "" {
+		req.Config = json.RawMessage(p.flags.configJSON)
+	}
+	hints, err := loadHintsFile(p.flags.hintsFile)
+	if err != nil {
+		return nil, err
+	}
+	req.Hints = hints
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := p.clientset.CoreV1().RESTClient().Post().
+		Namespace(p.flags.namespace).
+		Resource("services").
+		Name(fmt.Sprintf("%s:%s", p.flags.serviceName, p.flags.servicePort)).
+		SubResource("proxy").
+		Suffix("whatif").
+		Body(body).
+		DoRaw()
+	if err != nil {
+		return nil, fmt.Errorf("dry-run failed: %v: %s", err, string(data))
+	}
+	result := &scorer.Result{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("can't parse the dry-run result: %v", err)
+	}
+	return result, nil
+}
+
+// diff prints the names whose priority would change comparing the published priorities
+// with a dry-run, a missing priority is shown as "-".
+func (p *plugin) diff() error {
+	live, err := p.getConfigMapYAML(p.flags.outConfigMapName, "priorities")
+	if err != nil {
+		return err
+	}
+	result, err := p.dryRun()
+	if err != nil {
+		return err
+	}
+	liveByName := prioritiesByName(live)
+	dryRunByName := prioritiesByName(result.Priorities)
+
+	names := []string{}
+	for name := range liveByName {
+		names = append(names, name)
+	}
+	for name := range dryRunByName {
+		if _, ok := liveByName[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	fmtPrio := func(m map[string]int, name string) string {
+		if prio, ok := m[name]; ok {
+			return fmt.Sprintf("%d", prio)
+		}
+		return "-"
+	}
+
+	changes := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "\tNAME\tLIVE\tDRY-RUN")
+	for _, name := range names {
+		livePrio, dryRunPrio := fmtPrio(liveByName, name), fmtPrio(dryRunByName, name)
+		mark := " "
+		if livePrio != dryRunPrio {
+			mark = "*"
+			changes++
+		} else if !p.flags.all {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", mark, name, livePrio, dryRunPrio)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d changes\n", changes)
+	return nil
+}
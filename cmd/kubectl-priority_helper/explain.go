@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer"
+)
+
+// explain prints the score components of the ASGs matching the name, that can be
+// the ASG name or the name used in the priorities when availability zones are ignored
+func (p *plugin) explain(name string) error {
+	cm, err := p.clientset.CoreV1().ConfigMaps(p.flags.namespace).
+		Get(p.flags.breakdownConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("can't get the breakdown, is the helper running with --breakdown-configmap=%s ? %v",
+			p.flags.breakdownConfigMapName, err)
+	}
+	var breakdown []scorer.ScoreBreakdown
+	if err := json.Unmarshal([]byte(cm.Data[scorer.BreakdownKey]), &breakdown); err != nil {
+		return fmt.Errorf("can't parse the breakdown: %v", err)
+	}
+
+	found := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, b := range breakdown {
+		if b.ASGName != name && b.Name != name {
+			continue
+		}
+		if found {
+			fmt.Fprintln(w)
+		}
+		found = true
+		market := "on-demand"
+		if b.IsSpot {
+			market = "spot"
+		}
+		fmt.Fprintf(w, "ASG:\t%s\n", b.ASGName)
+		if b.Name != b.ASGName {
+			fmt.Fprintf(w, "Name:\t%s\n", b.Name)
+		}
+		if b.Pool != "" {
+			fmt.Fprintf(w, "Pool:\t%s\n", b.Pool)
+		}
+		fmt.Fprintf(w, "Instance types:\t%s\n", strings.Join(b.InstanceTypes, ", "))
+		fmt.Fprintf(w, "Availability zone:\t%s\n", b.AvailabilityZone)
+		fmt.Fprintf(w, "Market:\t%s\n", market)
+		if b.HasPrice {
+			fmt.Fprintf(w, "Price:\t%.4f\n", b.Price)
+			if b.PriceUnit != "" && b.PriceUnit != "hour" {
+				fmt.Fprintf(w, "Price per %s:\t%.4f\n", b.PriceUnit, b.ComparedPrice)
+			}
+			if b.PriceNotNormalized {
+				fmt.Fprintf(w, "Price per hour:\t%.4f (unknown capacity, not normalized)\n", b.ComparedPrice)
+			}
+		} else {
+			fmt.Fprintf(w, "Price:\tunknown\n")
+		}
+		if b.IsSpot {
+			fmt.Fprintf(w, "Interruption probability:\t%.2f\n", b.Probability)
+			if b.SpotPriceRatio > 0 {
+				expensive := ""
+				if b.ExpensiveSpot {
+					expensive = " (above the threshold, no spot bonuses)"
+				}
+				fmt.Fprintf(w, "Spot/on-demand price:\t%.2f%s\n", b.SpotPriceRatio, expensive)
+			}
+			if b.Savings >= 0 {
+				fmt.Fprintf(w, "Savings:\t%.0f%%\n", b.Savings)
+			}
+		}
+		fmt.Fprintf(w, "Nodes:\t%d\n", b.NodeCount)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "COMPONENT\tPOINTS")
+		components := b.Components()
+		for _, component := range scorer.ComponentNames {
+			if points := components[component]; points != 0 || component == "base" {
+				fmt.Fprintf(w, "%s\t%+d\n", component, points)
+			}
+		}
+		fmt.Fprintf(w, "priority\t%d\n", b.Priority)
+		if b.Vetoed {
+			fmt.Fprintf(w, "vetoed by the external scorer\t%s\n", b.ExternalReason)
+		} else if b.ExternalReason != "" {
+			fmt.Fprintf(w, "external scorer reason\t%s\n", b.ExternalReason)
+		}
+		if len(b.Derived) > 0 {
+			names := make([]string, 0, len(b.Derived))
+			for name := range b.Derived {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "DERIVED COEFFICIENT\tVALUE")
+			for _, name := range names {
+				fmt.Fprintf(w, "%s\t%d\n", name, b.Derived[name])
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("ASG %s not found in the breakdown", name)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+func sortedPriorities(priorities map[int][]string) []int {
+	res := []int{}
+	for prio := range priorities {
+		res = append(res, prio)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(res)))
+	return res
+}
+
+func printTiers(w *tabwriter.Writer, title string, priorities map[int][]string) {
+	fmt.Fprintf(w, "%s\tPRIORITY\tVALUES\n", title)
+	for _, prio := range sortedPriorities(priorities) {
+		values := append([]string{}, priorities[prio]...)
+		sort.Strings(values)
+		fmt.Fprintf(w, "\t%d\t%s\n", prio, strings.Join(values, ", "))
+	}
+}
+
+// get prints the published priorities grouped by tier, the highest first, followed by the hints
+func (p *plugin) get() error {
+	priorities, err := p.getConfigMapYAML(p.flags.outConfigMapName, "priorities")
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	printTiers(w, "PRIORITIES", priorities)
+
+	for _, key := range []string{"bonus", "malus", "priorities"} {
+		hints, err := p.getConfigMapYAML(p.flags.hintsConfigMapName, key)
+		if errors.IsNotFound(err) {
+			break
+		} else if err != nil {
+			return err
+		}
+		if len(hints) > 0 {
+			fmt.Fprintln(w)
+			printTiers(w, "HINTS "+strings.ToUpper(key), hints)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// editHints adds or removes the value to the list of the priority, it returns an error
+// when there is nothing to do so the caller can report it.
+func editHints(hints map[int][]string, action string, prio int, value string) error {
+	values := hints[prio]
+	idx := -1
+	for i, v := range values {
+		if v == value {
+			idx = i
+			break
+		}
+	}
+	switch action {
+	case "add":
+		if idx >= 0 {
+			return fmt.Errorf("%q is already present for priority %d", value, prio)
+		}
+		hints[prio] = append(values, value)
+	case "remove":
+		if idx < 0 {
+			return fmt.Errorf("%q is not present for priority %d", value, prio)
+		}
+		values = append(values[:idx:idx], values[idx+1:]...)
+		if len(values) == 0 {
+			delete(hints, prio)
+		} else {
+			hints[prio] = values
+		}
+	default:
+		return fmt.Errorf("unknown hint action: %s", action)
+	}
+	return nil
+}
+
+// hint edits one of the keys of the hints ConfigMap, the YAML is parsed and validated
+// before and after the change and the update fails in case of concurrent modifications
+// (then it is retried on the fresh ConfigMap).
+func (p *plugin) hint(action, key, prioStr, value string) error {
+	prio, err := strconv.Atoi(prioStr)
+	if err != nil {
+		return fmt.Errorf("invalid priority %s: %v", prioStr, err)
+	}
+	switch key {
+	case "bonus", "malus":
+		if _, err := regexp.Compile(value); err != nil {
+			return fmt.Errorf("invalid regexp %s: %v", value, err)
+		}
+	case "priorities":
+	default:
+		return fmt.Errorf("unknown hints key %s, expected bonus, malus or priorities", key)
+	}
+
+	cms := p.clientset.CoreV1().ConfigMaps(p.flags.namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := cms.Get(p.flags.hintsConfigMapName, metav1.GetOptions{})
+		create := false
+		if errors.IsNotFound(err) {
+			create = true
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: p.flags.namespace,
+					Name:      p.flags.hintsConfigMapName,
+				},
+			}
+		} else if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+
+		hints := make(map[int][]string)
+		if data, ok := cm.Data[key]; ok {
+			if err := yaml.Unmarshal([]byte(data), &hints); err != nil {
+				return fmt.Errorf("can't parse %s in %s/%s, fix it manually: %v",
+					key, p.flags.namespace, p.flags.hintsConfigMapName, err)
+			}
+			if hints == nil {
+				hints = make(map[int][]string)
+			}
+		}
+		if err := editHints(hints, action, prio, value); err != nil {
+			return err
+		}
+		data, err := yaml.Marshal(hints)
+		if err != nil {
+			return err
+		}
+		cm.Data[key] = string(data)
+		for _, k := range []string{"bonus", "malus", "priorities"} {
+			if _, ok := cm.Data[k]; !ok {
+				cm.Data[k] = "{}"
+			}
+		}
+
+		if create {
+			_, err = cms.Create(cm)
+		} else {
+			_, err = cms.Update(cm)
+		}
+		return err
+	})
+}
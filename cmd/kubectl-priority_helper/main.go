@@ -0,0 +1,151 @@
+// kubectl-priority_helper is a kubectl plugin, once in the PATH it is available as
+// `kubectl priority-helper` to inspect, explain and hint the priorities computed by
+// the cluster-autoscaler-priority-helper.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	priorityConfigMapName  = "cluster-autoscaler-priority-expander"
+	hintsConfigMapName     = "cluster-autoscaler-priority-hints"
+	breakdownConfigMapName = "cluster-autoscaler-priority-breakdown"
+	serviceName            = "cluster-autoscaler-priority-helper"
+	systemNamespace        = "kube-system"
+)
+
+const usage = `Usage: kubectl priority-helper <command> [flags]
+
+Commands:
+  get                                          show the published priorities grouped by tier and the hints
+  explain <asg>                                show the score components of an ASG (needs the breakdown ConfigMap)
+  hint add <bonus|malus|priorities> <prio> <value>
+                                               add a rule (a regexp for bonus and malus, an ASG name for priorities)
+  hint remove <bonus|malus|priorities> <prio> <value>
+                                               remove a rule
+  diff                                         compare the published priorities with a dry-run of the helper
+
+Flags:
+`
+
+type pluginFlags struct {
+	kubeconfig string
+	overrides  *clientcmd.ConfigOverrides
+	namespace  string
+
+	outConfigMapName       string
+	hintsConfigMapName     string
+	breakdownConfigMapName string
+	serviceName            string
+	servicePort            string
+
+	configJSON string
+	hintsFile  string
+	all        bool
+}
+
+type plugin struct {
+	flags     *pluginFlags
+	clientset clientset.Interface
+}
+
+func parsePluginFlags(args []string) (*pluginFlags, []string) {
+	flags := &pluginFlags{overrides: &clientcmd.ConfigOverrides{}}
+	fs := flag.NewFlagSet("kubectl-priority_helper", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	clientcmd.BindOverrideFlags(
+		flags.overrides, fs,
+		clientcmd.ConfigOverrideFlags{
+			CurrentContext: clientcmd.FlagInfo{
+				LongName:    clientcmd.FlagContext,
+				Description: "The name of the kubeconfig context to use",
+			},
+		})
+	fs.StringVar(&flags.kubeconfig, clientcmd.RecommendedConfigPathFlag, "", "kubeconfig path")
+	fs.StringVarP(&flags.namespace, "namespace", "n", systemNamespace, "Namespace of the helper and of its ConfigMaps")
+	fs.StringVar(&flags.outConfigMapName, "output-configmap", priorityConfigMapName, "")
+	fs.StringVar(&flags.hintsConfigMapName, "hints-configmap", hintsConfigMapName, "")
+	fs.StringVar(&flags.breakdownConfigMapName, "breakdown-configmap", breakdownConfigMapName, "")
+	fs.StringVar(&flags.serviceName, "service", serviceName, "Service exposing the helper HTTP server, used by diff")
+	fs.StringVar(&flags.servicePort, "service-port", "8080", "Port (name or number) of the service, used by diff")
+	fs.StringVar(&flags.configJSON, "config", "", "diff: partial scorer configuration as JSON, like {\"malusForPrice\": 200}")
+	fs.StringVar(&flags.hintsFile, "hints-file", "", "diff: YAML file with bonus, malus and priorities hints added to the current ones")
+	fs.BoolVar(&flags.all, "all", false, "diff: show also the ASGs with unchanged priority")
+	fs.Parse(args)
+	return flags, fs.Args()
+}
+
+func newPlugin(flags *pluginFlags) (*plugin, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = flags.kubeconfig
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, flags.overrides).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	cs, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin{flags: flags, clientset: cs}, nil
+}
+
+// getConfigMapYAML returns the YAML value of a key of a ConfigMap as map of priorities to list of strings
+func (p *plugin) getConfigMapYAML(name, key string) (map[int][]string, error) {
+	res := make(map[int][]string)
+	cm, err := p.clientset.CoreV1().ConfigMaps(p.flags.namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal([]byte(cm.Data[key]), &res); err != nil {
+		return nil, fmt.Errorf("can't parse %s in %s/%s: %v", key, p.flags.namespace, name, err)
+	}
+	return res, nil
+}
+
+func run(args []string) error {
+	flags, args := parsePluginFlags(args)
+	if len(args) == 0 {
+		return fmt.Errorf("a command is required, see --help")
+	}
+	p, err := newPlugin(flags)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "get":
+		return p.get()
+	case "explain":
+		if len(args) != 2 {
+			return fmt.Errorf("explain needs exactly one ASG name")
+		}
+		return p.explain(args[1])
+	case "hint":
+		if len(args) != 5 {
+			return fmt.Errorf("usage: hint <add|remove> <bonus|malus|priorities> <prio> <value>")
+		}
+		return p.hint(args[1], args[2], args[3], args[4])
+	case "diff":
+		return p.diff()
+	default:
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
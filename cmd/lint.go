@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/aws"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/lint"
+)
+
+type lintFlags struct {
+	autoDiscoverASGsByTags string
+	ignoreAZs              bool
+	output                 string
+}
+
+func parseLintFlags(args []string) *lintFlags {
+	flags := &lintFlags{}
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.StringVar(&flags.autoDiscoverASGsByTags, "auto-discover-asg-by-tags", "", "")
+	fs.BoolVar(&flags.ignoreAZs, "ignore-availability-zones", false, "Check also the names used to group the spot ASGs ignoring the AZs")
+	fs.StringVar(&flags.output, "output", "text", "Output format: text or json")
+	fs.Parse(args)
+	return flags
+}
+
+// runLint validates the discovered ASGs against the conventions, it fails when there are violations
+func runLint(args []string) error {
+	flags := parseLintFlags(args)
+	asgD, err := aws.NewASGDiscoverer(0, parseAutoDiscoverASGsByTags(flags.autoDiscoverASGsByTags))
+	if err != nil {
+		return err
+	}
+	infos, err := asgD.DescribeASGs()
+	if err != nil {
+		return err
+	}
+	violations := lint.Check(infos, flags.ignoreAZs)
+
+	switch flags.output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(violations); err != nil {
+			return err
+		}
+	case "text":
+		for _, v := range violations {
+			fmt.Println(v.String())
+		}
+		fmt.Printf("%d ASGs checked, %d violations\n", len(infos), len(violations))
+	default:
+		return fmt.Errorf("unknown output format: %s", flags.output)
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("%d violations found", len(violations))
+	}
+	return nil
+}
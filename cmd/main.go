@@ -5,13 +5,23 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
 	"syscall"
 
+	"k8s.io/klog"
+
 	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/aws"
-	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/nodes"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/crd"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/extscorer"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/history"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/metrics"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/notifier"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/profiles"
 	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer"
+	scorerconfig "github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/server"
 	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/spotadvisor"
-	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/utils"
 )
 
 const priorityConfigMapName = "cluster-autoscaler-priority-expander"
@@ -21,6 +31,22 @@ var version string
 
 func main() {
 	var err error
+	if len(os.Args) > 1 {
+		subcommands := map[string]func([]string) error{
+			"backtest":             runBacktest,
+			"report":               runReport,
+			"lint":                 runLint,
+			"external-scorer-stub": runExternalScorerStub,
+		}
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s failed: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+	}
+
 	flags := parseFlags()
 
 	if flags.version {
@@ -28,44 +54,211 @@ func main() {
 		os.Exit(0)
 	}
 
-	cs, err := utils.GetClientset(flags.kubeconfig, flags.overrides)
+	sad, err := spotadvisor.NewSpotAdvisor(flags.spotAdvisorRefreshInterval)
 	if err != nil {
 		panic(err.Error())
 	}
 
-	sad, err := spotadvisor.NewSpotAdvisor(flags.spotAdvisorRefreshInterval)
+	pricer, err := aws.NewPricer(flags.pricerRefreshInterval)
 	if err != nil {
 		panic(err.Error())
 	}
 
-	nd, err := nodes.NewNodesDistribution(cs)
+	targets, err := buildTargets(flags)
 	if err != nil {
 		panic(err.Error())
 	}
+	clusters := newClusters(flags)
 
-	asgD, err := aws.NewASGDiscoverer(flags.asgDiscovererRefreshInterval,
-		parseAutoDiscoverASGsByTags(flags.autoDiscoverASGsByTags))
-	if err != nil {
-		panic(err.Error())
+	var n *notifier.Notifier
+	if flags.webhooksConfig != "" {
+		webhooks, err := notifier.LoadWebhooksConfig(flags.webhooksConfig)
+		if err != nil {
+			panic(err.Error())
+		}
+		if n, err = notifier.NewNotifier(webhooks); err != nil {
+			panic(err.Error())
+		}
 	}
 
-	pricer, err := aws.NewPricer(flags.pricerRefreshInterval)
-	if err != nil {
-		panic(err.Error())
+	var ext *extscorer.Client
+	if flags.externalScorer.URL != "" {
+		if ext, err = extscorer.NewClient(flags.externalScorer); err != nil {
+			panic(err.Error())
+		}
+	}
+
+	stopCh := make(chan struct{})
+	var srv *server.Server
+	if flags.httpAddress != "" {
+		srv = server.NewServer(flags.httpAddress)
+		srv.Handle("/metrics", metrics.Handler())
+	}
+
+	scorers := []*scorer.Scorer{}
+	for i, target := range targets {
+		// the spot advisor and the prices are shared by all the targets,
+		// the nodes distribution by the targets of the same cluster
+		cl, err := clusters.get(target.Context)
+		if err != nil {
+			panic(err.Error())
+		}
+		asgD, err := aws.NewASGDiscoverer(flags.asgDiscovererRefreshInterval,
+			parseAutoDiscoverASGsByTags(target.AutoDiscoverASGsByTags))
+		if err != nil {
+			panic(err.Error())
+		}
+
+		s := scorer.NewScorer(
+			context.Background(), flags.leaderElection,
+			cl.clientset, target.OutputConfigMapName, target.Namespace, flags.scorerRefreshInterval,
+			sad, asgD, cl.nodesDistribution, pricer,
+			target.Scorer)
+		s.SetTarget(target.Name)
+		s.SetCluster(target.Context)
+
+		if target.BreakdownConfigMapName != "" {
+			s.SetBreakdownConfigMap(target.BreakdownConfigMapName)
+		}
+		if cl.recorder != nil {
+			s.SetEventRecorder(cl.recorder)
+		}
+		if n != nil {
+			s.SetNotifier(n)
+		}
+		if ext != nil {
+			s.SetExternalScorer(ext)
+		}
+
+		if flags.cloudWatchNamespace != "" {
+			s.AddRunnable(scorer.NewCloudWatchReporter(
+				s, aws.NewCloudWatchPublisher(flags.cloudWatchNamespace), flags.cloudWatchInterval))
+		}
+
+		if flags.configResources {
+			s.AddRunnable(crd.NewController(cl.dynamic, s, target.Namespace))
+		}
+
+		var conventionsChecker *scorer.ConventionsChecker
+		if flags.lintInterval > 0 {
+			conventionsChecker = scorer.NewConventionsChecker(s, flags.lintInterval)
+			s.AddRunnable(conventionsChecker)
+		}
+
+		if flags.recordHistoryDir != "" {
+			dir := flags.recordHistoryDir
+			if len(targets) > 1 {
+				dir = filepath.Join(dir, target.Name)
+			}
+			store, err := history.NewStore(dir)
+			if err != nil {
+				panic(err.Error())
+			}
+			store.SetRetention(flags.recordHistoryRetention, flags.recordHistoryMaxSizeMB<<20)
+			s.AddRunnable(history.NewRecorder(store, flags.recordHistoryInterval, s))
+		}
+
+		if srv != nil {
+			// every target is served under its own prefix, the first one also w/o prefix
+			prefixes := []string{"/targets/" + target.Name}
+			if i == 0 {
+				prefixes = append(prefixes, "")
+			}
+			for _, prefix := range prefixes {
+				srv.Handle(prefix+"/whatif", s.WhatIfHandler())
+				srv.Handle(prefix+"/dashboard", s.DashboardHandler())
+				for pattern, handler := range s.DebugHandlers() {
+					srv.Handle(prefix+pattern, handler)
+				}
+				if conventionsChecker != nil {
+					srv.Handle(prefix+"/debug/lint", conventionsChecker.Handler())
+				}
+			}
+			srv.Handle("/targets/"+target.Name+"/healthz", s.LivenessHandler())
+			srv.Handle("/targets/"+target.Name+"/readyz", s.ReadinessHandler())
+		}
+		scorers = append(scorers, s)
+	}
+
+	var profilesConfig *profiles.Config
+	if flags.profilesConfig != "" {
+		profilesConfig, err = loadProfiles(flags.profilesConfig, targets)
+		if err != nil {
+			panic(err.Error())
+		}
+		profiles.NewScheduler(profilesConfig, func(p profiles.Profile) {
+			for _, s := range scorers {
+				if err := s.SetProfile(p.Name, p.Overlay); err != nil {
+					klog.Errorf("Error applying profile %s to target %s: %v", p.Name, s.Target(), err)
+				}
+			}
+		}).Start(stopCh)
 	}
 
-	scorer := scorer.NewScorer(
-		context.Background(), flags.leaderElection,
-		cs, flags.outConfigMapName, systemNamespace, flags.scorerRefreshInterval,
-		sad, asgD, nd, pricer,
-		flags.scorerConfig)
+	if flags.configFile != "" && flags.configReloadInterval > 0 {
+		newConfigWatcher(flags, targets, scorers, profilesConfig).Start(stopCh)
+	}
+
+	if srv != nil {
+		srv.Handle("/healthz", scorer.LivenessHandlerFor(scorers))
+		srv.Handle("/readyz", scorer.ReadinessHandlerFor(scorers))
+		srv.Start(stopCh)
+	}
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGQUIT, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-c
-		scorer.Exit()
+		close(stopCh)
+		var wg sync.WaitGroup
+		for _, s := range scorers {
+			wg.Add(1)
+			go func(s *scorer.Scorer) {
+				defer wg.Done()
+				s.Exit()
+			}(s)
+		}
+		wg.Wait()
 	}()
 
-	scorer.Run()
+	// every target has its own leader election, in its own cluster
+	var wg sync.WaitGroup
+	for _, s := range scorers {
+		wg.Add(1)
+		go func(s *scorer.Scorer) {
+			defer wg.Done()
+			s.Run()
+		}(s)
+	}
+	wg.Wait()
+}
+
+// buildTargets returns the targets from --targets-config or --contexts, or the default one,
+// the missing settings are taken from the flags.
+func buildTargets(flags *Flags) ([]scorerconfig.Target, error) {
+	defaultTarget := scorerconfig.Target{
+		Name:                   scorerconfig.DefaultTargetName,
+		Namespace:              flags.namespace,
+		AutoDiscoverASGsByTags: flags.autoDiscoverASGsByTags,
+		OutputConfigMapName:    flags.outConfigMapName,
+		BreakdownConfigMapName: flags.breakdownConfigMapName,
+		Scorer:                 flags.scorerConfig,
+	}
+	switch {
+	case flags.targetsConfig != "" && len(flags.contexts) > 0:
+		return nil, fmt.Errorf("--contexts can't be used with --targets-config, set the context of every target")
+	case flags.targetsConfig != "":
+		return scorerconfig.LoadTargets(flags.targetsConfig, defaultTarget)
+	case len(flags.contexts) > 0:
+		// a target with the default configuration for every cluster
+		targets := []scorerconfig.Target{}
+		for _, context := range flags.contexts {
+			target := defaultTarget
+			target.Name = scorerconfig.TargetNameForContext(context)
+			target.Context = context
+			targets = append(targets, target)
+		}
+		return targets, scorerconfig.ValidateTargets(targets)
+	}
+	return []scorerconfig.Target{defaultTarget}, nil
 }
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/aws"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/nodes"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer"
+	scorerconfig "github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/spotadvisor"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/utils"
+)
+
+const advisorOSType = "Linux"
+
+type reportFlags struct {
+	kubeconfig             string
+	namespace              string
+	overrides              *clientcmd.ConfigOverrides
+	autoDiscoverASGsByTags string
+	output                 string
+
+	scorerConfig scorerconfig.ScorerConfiguration
+}
+
+// reportRow describes the economics of an ASG, for ASGs with mixed instance types the prices
+// and the savings are averages, vCPUs and memory are the minimum and the interruption range
+// is the worst one. Unknown numeric values are -1.
+type reportRow struct {
+	ASGName           string   `json:"asgName"`
+	InstanceTypes     []string `json:"instanceTypes"`
+	AvailabilityZone  string   `json:"availabilityZone"`
+	Market            string   `json:"market"`
+	OnDemandPrice     float64  `json:"onDemandPrice"`
+	SpotPrice         float64  `json:"spotPrice"`
+	SpotSavings       int      `json:"spotSavings"`
+	InterruptionRange string   `json:"interruptionRange"`
+	VCPUs             int      `json:"vcpus"`
+	MemoryGiB         int      `json:"memoryGiB"`
+	NodeCount         int      `json:"nodeCount"`
+	Priority          int      `json:"priority"`
+}
+
+var reportHeader = []string{
+	"ASG", "Instance types", "AZ", "Market", "On-demand price", "Spot price", "Spot savings %",
+	"Interruption range", "vCPUs", "Memory GiB", "Nodes", "Priority",
+}
+
+func parseReportFlags(args []string) (*reportFlags, error) {
+	flags := &reportFlags{overrides: &clientcmd.ConfigOverrides{}}
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	clientcmd.BindOverrideFlags(
+		flags.overrides, fs,
+		clientcmd.ConfigOverrideFlags{
+			CurrentContext: clientcmd.FlagInfo{
+				LongName:    clientcmd.FlagContext,
+				Description: "The name of the kubeconfig context to use",
+			},
+		})
+	scorerconfig.BindFlags(&flags.scorerConfig, fs)
+	fs.StringVar(&flags.kubeconfig, clientcmd.RecommendedConfigPathFlag, "", "kubeconfig path")
+	fs.StringVar(&flags.namespace, "namespace", systemNamespace, "Namespace of the hints ConfigMap")
+	fs.StringVar(&flags.autoDiscoverASGsByTags, "auto-discover-asg-by-tags", "", "")
+	fs.StringVar(&flags.output, "output", "csv", "Output format: csv, json or markdown")
+	fs.Parse(args)
+	scorerconfig.ApplyDialFlags(&flags.scorerConfig, fs)
+	if err := scorerconfig.Validate(flags.scorerConfig); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// getHintsSpec reads the hints ConfigMap, if it is missing there are no hints
+func getHintsSpec(cs clientset.Interface, namespace, name string) (scorer.HintsSpec, error) {
+	hints := scorer.HintsSpec{}
+	cm, err := cs.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return hints, nil
+	} else if err != nil {
+		return hints, err
+	}
+	for key, rules := range map[string]*map[int][]string{
+		"bonus":      &hints.Bonus,
+		"malus":      &hints.Malus,
+		"priorities": &hints.Priorities,
+	} {
+		if err := yaml.Unmarshal([]byte(cm.Data[key]), rules); err != nil {
+			return hints, fmt.Errorf("can't parse %s in the hints config map: %v", key, err)
+		}
+	}
+	return hints, nil
+}
+
+// fetchSources fetches the data once, the sources are stopped when the returned function is called
+func fetchSources(sad *spotadvisor.SpotAdvisor, asgD *aws.ASGDiscoverer, pricer *aws.Pricer, nd *nodes.NodesDistribution) (func(), error) {
+	stopCh := make(chan struct{})
+	changesCh := make(chan struct{}, 1)
+	stop := func() { close(stopCh) }
+	// the ASGs have to be discovered first to know the instance types to price
+	if err := asgD.Start(stopCh, changesCh); err != nil {
+		stop()
+		return nil, err
+	}
+	if err := pricer.Start(stopCh, changesCh); err != nil {
+		stop()
+		return nil, err
+	}
+	if err := sad.Start(stopCh, changesCh); err != nil {
+		stop()
+		return nil, err
+	}
+	if err := nd.Start(stopCh, changesCh); err != nil {
+		stop()
+		return nil, err
+	}
+	return stop, nil
+}
+
+func buildReport(snap *scorer.Snapshot, result *scorer.Result, sad *spotadvisor.SpotAdvisor) []reportRow {
+	rows := []reportRow{}
+	for _, b := range result.Breakdown {
+		asgSnap := snap.ASGs[b.ASGName]
+		region := (utils.InstanceDetails{AvailabilityZone: b.AvailabilityZone}).GetRegion()
+		row := reportRow{
+			ASGName:          b.ASGName,
+			InstanceTypes:    b.InstanceTypes,
+			AvailabilityZone: b.AvailabilityZone,
+			Market:           "on-demand",
+			OnDemandPrice:    -1,
+			SpotPrice:        -1,
+			SpotSavings:      -1,
+			VCPUs:            -1,
+			MemoryGiB:        -1,
+			NodeCount:        b.NodeCount,
+			Priority:         -1,
+		}
+		if b.IsSpot {
+			row.Market = "spot"
+		}
+
+		var onDemandSum, spotSum float64
+		var onDemandCount, spotCount, savingsSum, savingsCount, worstRange int
+		worstRange = -1
+		for _, it := range asgSnap.InstanceTypes {
+			if price, found := snap.GetPriceFor(it, b.AvailabilityZone, false); found {
+				onDemandSum += price
+				onDemandCount++
+			}
+			if price, found := snap.GetPriceFor(it, b.AvailabilityZone, true); found {
+				spotSum += price
+				spotCount++
+			}
+			if saving := snap.GetSavingFor(region, it); saving >= 0 {
+				savingsSum += saving
+				savingsCount++
+			}
+			if r := snap.GetProbabilityFor(region, it); r > worstRange {
+				worstRange = r
+				row.InterruptionRange = sad.GetRangeLabelFor(region, advisorOSType, it)
+			}
+			if cores := sad.GetCoresFor(it); cores >= 0 && (row.VCPUs < 0 || cores < row.VCPUs) {
+				row.VCPUs = cores
+			}
+			if ram := sad.GetRamGbFor(it); ram >= 0 && (row.MemoryGiB < 0 || ram < row.MemoryGiB) {
+				row.MemoryGiB = ram
+			}
+		}
+		if onDemandCount > 0 {
+			row.OnDemandPrice = onDemandSum / float64(onDemandCount)
+		}
+		if spotCount > 0 {
+			row.SpotPrice = spotSum / float64(spotCount)
+		}
+		if savingsCount > 0 {
+			row.SpotSavings = savingsSum / savingsCount
+		}
+
+		// the published priority of the name, it can be hinted
+		for prio, names := range result.Priorities {
+			for _, name := range names {
+				if name == b.Name && prio > row.Priority {
+					row.Priority = prio
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func formatFloat(v float64) string {
+	if v < 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', 4, 64)
+}
+
+func formatInt(v int) string {
+	if v < 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+func (r reportRow) cells() []string {
+	return []string{
+		r.ASGName, strings.Join(r.InstanceTypes, " "), r.AvailabilityZone, r.Market,
+		formatFloat(r.OnDemandPrice), formatFloat(r.SpotPrice), formatInt(r.SpotSavings),
+		r.InterruptionRange, formatInt(r.VCPUs), formatInt(r.MemoryGiB), formatInt(r.NodeCount), formatInt(r.Priority),
+	}
+}
+
+func writeReport(out io.Writer, format string, rows []reportRow) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case "csv":
+		w := csv.NewWriter(out)
+		w.Write(reportHeader)
+		for _, row := range rows {
+			w.Write(row.cells())
+		}
+		w.Flush()
+		return w.Error()
+	case "markdown":
+		fmt.Fprintf(out, "| %s |\n", strings.Join(reportHeader, " | "))
+		fmt.Fprintf(out, "|%s\n", strings.Repeat(" --- |", len(reportHeader)))
+		for _, row := range rows {
+			cells := row.cells()
+			for i := range cells {
+				cells[i] = strings.Replace(cells[i], "|", "\\|", -1)
+			}
+			fmt.Fprintf(out, "| %s |\n", strings.Join(cells, " | "))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+func runReport(args []string) error {
+	flags, err := parseReportFlags(args)
+	if err != nil {
+		return err
+	}
+	switch flags.output {
+	case "csv", "json", "markdown":
+	default:
+		return fmt.Errorf("unknown output format: %s", flags.output)
+	}
+
+	cs, err := utils.GetClientset(flags.kubeconfig, flags.overrides)
+	if err != nil {
+		return err
+	}
+	sad, err := spotadvisor.NewSpotAdvisor(time.Hour)
+	if err != nil {
+		return err
+	}
+	nd, err := nodes.NewNodesDistribution(cs)
+	if err != nil {
+		return err
+	}
+	asgD, err := aws.NewASGDiscoverer(time.Hour, parseAutoDiscoverASGsByTags(flags.autoDiscoverASGsByTags))
+	if err != nil {
+		return err
+	}
+	pricer, err := aws.NewPricer(time.Hour)
+	if err != nil {
+		return err
+	}
+	hints, err := getHintsSpec(cs, flags.namespace, flags.scorerConfig.HintsConfigMapName)
+	if err != nil {
+		return err
+	}
+
+	stop, err := fetchSources(sad, asgD, pricer, nd)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	snap := scorer.TakeSnapshot(asgD, pricer, sad, nd)
+	result, err := scorer.Compute(snap, flags.scorerConfig, hints)
+	if err != nil {
+		return err
+	}
+	return writeReport(os.Stdout, flags.output, buildReport(snap, result, sad))
+}
@@ -3,6 +3,7 @@ module github.com/safanaj/cluster-autoscaler-priority-helper
 go 1.13
 
 require (
+	github.com/Knetic/govaluate v3.0.0+incompatible
 	github.com/aws/aws-sdk-go v1.30.8
 	github.com/cristim/ec2-instances-info v0.0.0-20200313152655-3f08567bd2ad
 	github.com/evanphx/json-patch v4.5.0+incompatible // indirect
@@ -17,6 +18,8 @@ require (
 	github.com/modern-go/reflect2 v1.0.1 // indirect
 	github.com/onsi/ginkgo v1.11.0 // indirect
 	github.com/onsi/gomega v1.7.0 // indirect
+	github.com/prometheus/client_golang v0.9.2
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/spf13/pflag v1.0.5
 	golang.org/x/crypto v0.0.0-20200220183623-bac4c82f6975 // indirect
 	golang.org/x/net v0.0.0-20200324143707-d3edc9973b7e // indirect
@@ -35,5 +38,5 @@ require (
 	k8s.io/kube-openapi v0.0.0-20190816220812-743ec37842bf // indirect
 	k8s.io/kubernetes v1.14.8
 	k8s.io/utils v0.0.0-20200414100711-2df71ebbae66 // indirect
-	sigs.k8s.io/yaml v1.2.0 // indirect
+	sigs.k8s.io/yaml v1.2.0
 )
@@ -16,12 +16,31 @@ import (
 	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/utils"
 )
 
-// this is global and shared with the spotPricer
+// this is global and shared with the spotPricer, every discoverer has its own list
+// and the pricer fetches the prices of all of them
 var (
 	knownInstanceTypesListMu sync.RWMutex
-	knownInstanceTypesList   []*string
+	knownInstanceTypesLists  = make(map[*ASGDiscoverer][]string)
 )
 
+// knownInstanceTypesList returns the union of the instance types of the discovered ASGs
+func knownInstanceTypesList() []*string {
+	knownInstanceTypesListMu.RLock()
+	defer knownInstanceTypesListMu.RUnlock()
+	// nil, when no ASG was discovered yet, means all the instance types
+	var res []*string
+	seen := make(map[string]struct{})
+	for _, instanceTypes := range knownInstanceTypesLists {
+		for _, itype := range instanceTypes {
+			if _, ok := seen[itype]; !ok {
+				seen[itype] = struct{}{}
+				res = append(res, aws.String(itype))
+			}
+		}
+	}
+	return res
+}
+
 type ASGDiscoverer struct {
 	*fetcher.DataManager
 	session *session.Session
@@ -36,12 +55,14 @@ type ASGDiscoverer struct {
 	launchTemplateInstanceTypeCache      map[string]utils.InstanceDetails
 
 	asgToMixedInstanceTypesAndAZ map[string]utils.MixedInstanceTypesDetails
+
+	asgTags map[string]map[string]string
 }
 
 var _ fetcher.Fetcher = &ASGDiscoverer{}
 
 func NewASGDiscoverer(refreshInterval time.Duration, autoDiscoveryTags map[string]string) (*ASGDiscoverer, error) {
-	sess := session.New(getAwsConfig())
+	sess := NewSession()
 	asgDiscoverer := &ASGDiscoverer{
 		session:                              sess,
 		svc:                                  autoscaling.New(sess),
@@ -138,7 +159,16 @@ func (asgd *ASGDiscoverer) getInstanceTypeByLCName(name string) (utils.InstanceD
 	if instanceDetails, found := asgd.launchConfigurationInstanceTypeCache[name]; found {
 		return instanceDetails, nil
 	}
+	iDetails, err := asgd.describeLaunchConfiguration(name)
+	if err != nil {
+		return iDetails, err
+	}
+	asgd.launchConfigurationInstanceTypeCache[name] = iDetails
+	return iDetails, nil
+}
 
+// describeLaunchConfiguration asks AWS the instance type of the launch configuration, w/o using the cache
+func (asgd *ASGDiscoverer) describeLaunchConfiguration(name string) (utils.InstanceDetails, error) {
 	params := &autoscaling.DescribeLaunchConfigurationsInput{
 		LaunchConfigurationNames: []*string{aws.String(name)},
 		MaxRecords:               aws.Int64(1),
@@ -153,13 +183,10 @@ func (asgd *ASGDiscoverer) getInstanceTypeByLCName(name string) (utils.InstanceD
 	}
 
 	instanceType := *launchConfigurations.LaunchConfigurations[0].InstanceType
-	iDetails := utils.InstanceDetails{
+	return utils.InstanceDetails{
 		InstanceType: instanceType,
 		IsSpot:       (launchConfigurations.LaunchConfigurations[0].SpotPrice != nil),
-	}
-	asgd.launchConfigurationInstanceTypeCache[name] = iDetails
-
-	return iDetails, nil
+	}, nil
 }
 
 type launchTemplate struct {
@@ -168,15 +195,45 @@ type launchTemplate struct {
 	version string
 }
 
-func (asgd *ASGDiscoverer) getInstanceTypeByLT(launchTemplate *launchTemplate) (utils.InstanceDetails, error) {
-	ltCacheKey := fmt.Sprintf("%s---%s", launchTemplate.name, launchTemplate.version)
-	if launchTemplate.name == "" && launchTemplate.id != "" {
-		ltCacheKey = fmt.Sprintf("%s---%s", launchTemplate.id, launchTemplate.version)
+func newLaunchTemplate(spec *autoscaling.LaunchTemplateSpecification) *launchTemplate {
+	lt := &launchTemplate{
+		version: "$Default",
+	}
+	if spec.Version != nil {
+		lt.version = aws.StringValue(spec.Version)
+	}
+	if spec.LaunchTemplateName != nil {
+		lt.name = aws.StringValue(spec.LaunchTemplateName)
+	}
+	if spec.LaunchTemplateId != nil {
+		lt.id = aws.StringValue(spec.LaunchTemplateId)
+	}
+	return lt
+}
+
+// cacheKey is the key of the launch template in the cache, by name or by id when it has no name
+func (lt *launchTemplate) cacheKey() string {
+	if lt.name == "" && lt.id != "" {
+		return fmt.Sprintf("%s---%s", lt.id, lt.version)
 	}
-	if iDetails, found := asgd.launchTemplateInstanceTypeCache[ltCacheKey]; found {
+	return fmt.Sprintf("%s---%s", lt.name, lt.version)
+}
+
+func (asgd *ASGDiscoverer) getInstanceTypeByLT(launchTemplate *launchTemplate) (utils.InstanceDetails, error) {
+	if iDetails, found := asgd.launchTemplateInstanceTypeCache[launchTemplate.cacheKey()]; found {
 		return iDetails, nil
 	}
+	iDetails, err := asgd.describeLaunchTemplate(launchTemplate)
+	if err != nil {
+		return iDetails, err
+	}
+	asgd.cacheLaunchTemplate(launchTemplate, iDetails)
+	return iDetails, nil
+}
 
+// describeLaunchTemplate asks AWS the instance type of the launch template version, w/o using the cache,
+// the name and the id of the launch template are set from the response.
+func (asgd *ASGDiscoverer) describeLaunchTemplate(launchTemplate *launchTemplate) (utils.InstanceDetails, error) {
 	params := &ec2.DescribeLaunchTemplateVersionsInput{
 		Versions: []*string{aws.String(launchTemplate.version)},
 	}
@@ -208,14 +265,15 @@ func (asgd *ASGDiscoverer) getInstanceTypeByLT(launchTemplate *launchTemplate) (
 		return utils.InstanceDetails{}, fmt.Errorf("unable to find instance type within launch template")
 	}
 
-	iDetails := utils.InstanceDetails{InstanceType: aws.StringValue(instanceType), IsSpot: isSpot}
 	launchTemplate.name = aws.StringValue(lt.LaunchTemplateName)
 	launchTemplate.id = aws.StringValue(lt.LaunchTemplateId)
-	ltCacheKey = fmt.Sprintf("%s---%s", launchTemplate.name, launchTemplate.version)
-	asgd.launchTemplateInstanceTypeCache[ltCacheKey] = iDetails
-	ltCacheKey = fmt.Sprintf("%s---%s", launchTemplate.id, launchTemplate.version)
-	asgd.launchTemplateInstanceTypeCache[ltCacheKey] = iDetails
-	return iDetails, nil
+	return utils.InstanceDetails{InstanceType: aws.StringValue(instanceType), IsSpot: isSpot}, nil
+}
+
+// cacheLaunchTemplate caches the instance type of the launch template by name and by id
+func (asgd *ASGDiscoverer) cacheLaunchTemplate(launchTemplate *launchTemplate, iDetails utils.InstanceDetails) {
+	asgd.launchTemplateInstanceTypeCache[fmt.Sprintf("%s---%s", launchTemplate.name, launchTemplate.version)] = iDetails
+	asgd.launchTemplateInstanceTypeCache[fmt.Sprintf("%s---%s", launchTemplate.id, launchTemplate.version)] = iDetails
 }
 
 func (asgd *ASGDiscoverer) GetData() (interface{}, error) {
@@ -229,8 +287,8 @@ func (asgd *ASGDiscoverer) ProcessData(data interface{}) error {
 	instanceTypeAndAZToAsg := make(map[string]string)
 
 	asgToMixedInstanceTypesAndAZ := make(map[string]utils.MixedInstanceTypesDetails)
+	asgTags := make(map[string]map[string]string)
 
-	instanceTypesList := []*string{}
 	instanceTypesMap := make(map[string]struct{})
 
 	for _, asg := range r.AutoScalingGroups {
@@ -251,21 +309,7 @@ func (asgd *ASGDiscoverer) ProcessData(data interface{}) error {
 				return err
 			}
 		} else if asg.LaunchTemplate != nil {
-			var version string
-			if asg.LaunchTemplate.Version == nil {
-				version = "$Default"
-			} else {
-				version = aws.StringValue(asg.LaunchTemplate.Version)
-			}
-			lt := &launchTemplate{
-				version: version,
-			}
-			if asg.LaunchTemplate.LaunchTemplateName != nil {
-				lt.name = aws.StringValue(asg.LaunchTemplate.LaunchTemplateName)
-			}
-			if asg.LaunchTemplate.LaunchTemplateId != nil {
-				lt.id = aws.StringValue(asg.LaunchTemplate.LaunchTemplateId)
-			}
+			lt := newLaunchTemplate(asg.LaunchTemplate)
 			if iDetails, err = asgd.getInstanceTypeByLT(lt); err != nil {
 				err = fmt.Errorf("Error getting instance type from LT: %s, %v",
 					fmt.Sprintf("%s (v %s)", lt.name, lt.version), err)
@@ -273,23 +317,8 @@ func (asgd *ASGDiscoverer) ProcessData(data interface{}) error {
 				return err
 			}
 		} else if asg.MixedInstancesPolicy != nil {
-			var version string
 			var ltiDetails utils.InstanceDetails
-			ltSpec := asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
-			if ltSpec.Version == nil {
-				version = "$Default"
-			} else {
-				version = aws.StringValue(ltSpec.Version)
-			}
-			lt := &launchTemplate{
-				version: version,
-			}
-			if ltSpec.LaunchTemplateName != nil {
-				lt.name = aws.StringValue(ltSpec.LaunchTemplateName)
-			}
-			if ltSpec.LaunchTemplateId != nil {
-				lt.id = aws.StringValue(ltSpec.LaunchTemplateId)
-			}
+			lt := newLaunchTemplate(asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification)
 			if ltiDetails, err = asgd.getInstanceTypeByLT(lt); err != nil {
 				err = fmt.Errorf("Error getting instance type from LT: %s, %v",
 					fmt.Sprintf("%s (v %s)", lt.name, lt.version), err)
@@ -328,6 +357,11 @@ func (asgd *ASGDiscoverer) ProcessData(data interface{}) error {
 		}
 
 		asgName = aws.StringValue(asg.AutoScalingGroupName)
+		tags := make(map[string]string)
+		for _, tag := range asg.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		asgTags[asgName] = tags
 		// fill caches
 		if !isMixedInstances {
 			iDetails.AvailabilityZone = az
@@ -344,15 +378,15 @@ func (asgd *ASGDiscoverer) ProcessData(data interface{}) error {
 	asgd.asgToInstanceTypeAndAZ = asgToInstanceTypeAndAZ
 	asgd.instanceTypeAndAZToAsg = instanceTypeAndAZToAsg
 	asgd.asgToMixedInstanceTypesAndAZ = asgToMixedInstanceTypesAndAZ
+	asgd.asgTags = asgTags
 
 	instanceTypesStingList := []string{}
 	for itype, _ := range instanceTypesMap {
-		instanceTypesList = append(instanceTypesList, aws.String(itype))
 		instanceTypesStingList = append(instanceTypesStingList, itype)
 	}
 	knownInstanceTypesListMu.Lock()
 	defer knownInstanceTypesListMu.Unlock()
-	knownInstanceTypesList = instanceTypesList
+	knownInstanceTypesLists[asgd] = instanceTypesStingList
 	klog.V(4).Infof("knownInstanceTypesList: %v\n", instanceTypesStingList)
 
 	return nil
@@ -400,6 +434,22 @@ func (asgd *ASGDiscoverer) GetASGsData() (map[string]string, error) {
 	return asgd.asgToInstanceTypeAndAZ, nil
 }
 
+// GetAllDetails returns the details of all the discovered ASGs, mixed instance types included
+func (asgd *ASGDiscoverer) GetAllDetails() map[string]utils.DetailsResult {
+	asgd.DataManager.RLock()
+	defer asgd.DataManager.RUnlock()
+	res := make(map[string]utils.DetailsResult)
+	for asgName, s := range asgd.asgToInstanceTypeAndAZ {
+		iDetails := utils.InstanceDetails{}
+		(&iDetails).FromString(s)
+		res[asgName] = iDetails
+	}
+	for asgName, mDetails := range asgd.asgToMixedInstanceTypesAndAZ {
+		res[asgName] = mDetails
+	}
+	return res
+}
+
 func (asgd *ASGDiscoverer) GetAsgFromInstanceDetails(details utils.InstanceDetails) (string, error) {
 	asgd.DataManager.RLock()
 	defer asgd.DataManager.RUnlock()
@@ -421,7 +471,7 @@ func (asgd *ASGDiscoverer) GetInstanceDetailsFor(asgName string) (utils.Instance
 }
 
 func (asgd *ASGDiscoverer) GetAsgFor(instanceType, az string, isSpot bool) (string, error) {
-	if asgName, err := asgd.GetAsgFromInstanceDetails(utils.InstanceDetails{instanceType, az, isSpot}); err == nil {
+	if asgName, err := asgd.GetAsgFromInstanceDetails(utils.InstanceDetails{InstanceType: instanceType, AvailabilityZone: az, IsSpot: isSpot}); err == nil {
 		return asgName, nil
 	}
 	return "", fmt.Errorf("ASG not found for %s in %s (spot? %t)", instanceType, az, isSpot)
@@ -434,3 +484,14 @@ func (asgd *ASGDiscoverer) GetInstanceTypeAndAZFor(asgName string) ([]string, er
 	}
 	return []string{}, fmt.Errorf("No instance type and AZ found for %s", asgName)
 }
+
+// GetTagsFor returns a copy of the tags of the ASG
+func (asgd *ASGDiscoverer) GetTagsFor(asgName string) map[string]string {
+	asgd.DataManager.RLock()
+	defer asgd.DataManager.RUnlock()
+	res := make(map[string]string)
+	for k, v := range asgd.asgTags[asgName] {
+		res[k] = v
+	}
+	return res
+}
@@ -0,0 +1,117 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/utils"
+)
+
+// ASGInfo keeps the raw details of an ASG as described by AWS, they are used to validate
+// the conventions the discovery relies on (see the lint package).
+type ASGInfo struct {
+	Name              string   `json:"name"`
+	AvailabilityZones []string `json:"availabilityZones"`
+
+	LaunchConfigurationName string `json:"launchConfigurationName,omitempty"`
+	LaunchTemplateName      string `json:"launchTemplateName,omitempty"`
+	// InstanceType and IsSpot come from the launch configuration or the launch template,
+	// InstanceTypeError is set when they can't be resolved
+	InstanceType      string `json:"instanceType,omitempty"`
+	IsSpot            bool   `json:"isSpot"`
+	InstanceTypeError string `json:"instanceTypeError,omitempty"`
+
+	MixedInstancesPolicy                bool     `json:"mixedInstancesPolicy"`
+	OverridesInstanceTypes              []string `json:"overridesInstanceTypes,omitempty"`
+	OnDemandPercentageAboveBaseCapacity *int64   `json:"onDemandPercentageAboveBaseCapacity,omitempty"`
+	SpotAllocationStrategy              string   `json:"spotAllocationStrategy,omitempty"`
+}
+
+// DescribeASGs describes the ASGs matching the auto discovery tags, unlike the discovery
+// it doesn't fail when the instance type of an ASG can't be resolved, it is reported in the ASGInfo.
+func (asgd *ASGDiscoverer) DescribeASGs() ([]ASGInfo, error) {
+	out, err := asgd.getASGsByTags()
+	if err != nil {
+		return nil, err
+	}
+	res := []ASGInfo{}
+	for _, asg := range out.AutoScalingGroups {
+		info := ASGInfo{
+			Name:              aws.StringValue(asg.AutoScalingGroupName),
+			AvailabilityZones: aws.StringValueSlice(asg.AvailabilityZones),
+		}
+		var lt *launchTemplate
+		switch {
+		case aws.StringValue(asg.LaunchConfigurationName) != "":
+			info.LaunchConfigurationName = aws.StringValue(asg.LaunchConfigurationName)
+		case asg.LaunchTemplate != nil:
+			lt = newLaunchTemplate(asg.LaunchTemplate)
+		case asg.MixedInstancesPolicy != nil:
+			info.MixedInstancesPolicy = true
+			if asg.MixedInstancesPolicy.LaunchTemplate != nil {
+				lt = newLaunchTemplate(asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification)
+				for _, o := range asg.MixedInstancesPolicy.LaunchTemplate.Overrides {
+					if o.InstanceType != nil {
+						info.OverridesInstanceTypes = append(info.OverridesInstanceTypes, aws.StringValue(o.InstanceType))
+					}
+				}
+			}
+			if dist := asg.MixedInstancesPolicy.InstancesDistribution; dist != nil {
+				info.OnDemandPercentageAboveBaseCapacity = dist.OnDemandPercentageAboveBaseCapacity
+				info.SpotAllocationStrategy = aws.StringValue(dist.SpotAllocationStrategy)
+			}
+		}
+		if lt != nil {
+			info.LaunchTemplateName = lt.name
+			if info.LaunchTemplateName == "" {
+				info.LaunchTemplateName = lt.id
+			}
+		}
+
+		iDetails, err := asgd.resolveInstanceType(info.LaunchConfigurationName, lt)
+		if err != nil {
+			info.InstanceTypeError = err.Error()
+		}
+		info.InstanceType = iDetails.InstanceType
+		info.IsSpot = iDetails.IsSpot
+		res = append(res, info)
+	}
+	return res, nil
+}
+
+// resolveInstanceType returns the instance type of the launch configuration or template using the
+// caches shared with the discovery, the lock is not held while AWS is called on a cache miss.
+func (asgd *ASGDiscoverer) resolveInstanceType(lcName string, lt *launchTemplate) (utils.InstanceDetails, error) {
+	if lcName == "" && lt == nil {
+		return utils.InstanceDetails{}, nil
+	}
+	asgd.DataManager.RLock()
+	var iDetails utils.InstanceDetails
+	var found bool
+	if lcName != "" {
+		iDetails, found = asgd.launchConfigurationInstanceTypeCache[lcName]
+	} else {
+		iDetails, found = asgd.launchTemplateInstanceTypeCache[lt.cacheKey()]
+	}
+	asgd.DataManager.RUnlock()
+	if found {
+		return iDetails, nil
+	}
+
+	var err error
+	if lcName != "" {
+		iDetails, err = asgd.describeLaunchConfiguration(lcName)
+	} else {
+		iDetails, err = asgd.describeLaunchTemplate(lt)
+	}
+	if err != nil {
+		return iDetails, err
+	}
+	asgd.DataManager.Lock()
+	defer asgd.DataManager.Unlock()
+	if lcName != "" {
+		asgd.launchConfigurationInstanceTypeCache[lcName] = iDetails
+	} else {
+		asgd.cacheLaunchTemplate(lt, iDetails)
+	}
+	return iDetails, nil
+}
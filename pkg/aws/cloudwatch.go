@@ -0,0 +1,101 @@
+package aws
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"k8s.io/klog"
+)
+
+const (
+	// PutMetricData accepts no more than 1000 metrics (and 1MB) per request
+	cloudWatchMaxDatumsPerRequest = 1000
+	cloudWatchMaxDimensions       = 10
+	cloudWatchMaxDimensionValue   = 255
+)
+
+// MetricDatum is a single CloudWatch data point
+type MetricDatum struct {
+	Name       string
+	Value      float64
+	Unit       string
+	Dimensions map[string]string
+	Timestamp  time.Time
+}
+
+type CloudWatchPublisher struct {
+	svc       *cloudwatch.CloudWatch
+	namespace string
+}
+
+func NewCloudWatchPublisher(namespace string) *CloudWatchPublisher {
+	return &CloudWatchPublisher{
+		svc:       cloudwatch.New(NewSession()),
+		namespace: namespace,
+	}
+}
+
+func toCloudWatchDatum(d MetricDatum) *cloudwatch.MetricDatum {
+	unit := d.Unit
+	if unit == "" {
+		unit = cloudwatch.StandardUnitNone
+	}
+	datum := &cloudwatch.MetricDatum{
+		MetricName: aws.String(d.Name),
+		Value:      aws.Float64(d.Value),
+		Unit:       aws.String(unit),
+		Timestamp:  aws.Time(d.Timestamp),
+	}
+	names := []string{}
+	for name := range d.Dimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(datum.Dimensions) == cloudWatchMaxDimensions {
+			klog.Warningf("CloudWatch metric %s has too many dimensions, dropping %s", d.Name, name)
+			continue
+		}
+		value := d.Dimensions[name]
+		if value == "" {
+			continue
+		}
+		if len(value) > cloudWatchMaxDimensionValue {
+			value = value[:cloudWatchMaxDimensionValue]
+		}
+		datum.Dimensions = append(datum.Dimensions, &cloudwatch.Dimension{
+			Name:  aws.String(name),
+			Value: aws.String(value),
+		})
+	}
+	return datum
+}
+
+// Publish sends the data points in batches respecting the PutMetricData limits,
+// values not supported by CloudWatch (NaN, Inf) are skipped.
+func (p *CloudWatchPublisher) Publish(data []MetricDatum) error {
+	datums := []*cloudwatch.MetricDatum{}
+	for _, d := range data {
+		if math.IsNaN(d.Value) || math.IsInf(d.Value, 0) {
+			continue
+		}
+		datums = append(datums, toCloudWatchDatum(d))
+	}
+	for i := 0; i < len(datums); i += cloudWatchMaxDatumsPerRequest {
+		end := i + cloudWatchMaxDatumsPerRequest
+		if end > len(datums) {
+			end = len(datums)
+		}
+		if _, err := p.svc.PutMetricData(&cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(p.namespace),
+			MetricData: datums[i:end],
+		}); err != nil {
+			return err
+		}
+	}
+	klog.V(3).Infof("Published %d metrics to CloudWatch namespace %s", len(datums), p.namespace)
+	return nil
+}
@@ -30,7 +30,7 @@ type Pricer struct {
 var _ fetcher.Fetcher = &Pricer{}
 
 func NewPricer(refreshInterval time.Duration) (*Pricer, error) {
-	sess := session.New(getAwsConfig())
+	sess := NewSession()
 	pricer := &Pricer{
 		session: sess,
 		svc:     ec2.New(sess),
@@ -50,9 +50,7 @@ type pricesData struct {
 }
 
 func (p *Pricer) GetData() (interface{}, error) {
-	knownInstanceTypesListMu.Lock()
-	instanceTypesList := knownInstanceTypesList
-	knownInstanceTypesListMu.Unlock()
+	instanceTypesList := knownInstanceTypesList()
 
 	c := make(chan *ec2instancesinfo.InstanceData)
 	go func() {
@@ -159,3 +157,19 @@ func (p *Pricer) GetPriceFor(instanceType, az string, isSpot bool) (float64, boo
 	}
 
 }
+
+// GetPrices returns a copy of the spot prices (by instance type and AZ) and of
+// the on-demand prices (by instance type and region)
+func (p *Pricer) GetPrices() (map[string]float64, map[string]float64) {
+	p.DataManager.RLock()
+	defer p.DataManager.RUnlock()
+	spot := make(map[string]float64, len(p.instanceTypeAndAZToPrice))
+	for k, v := range p.instanceTypeAndAZToPrice {
+		spot[k] = v
+	}
+	ondemand := make(map[string]float64, len(p.instanceTypeAndRegionToPrice))
+	for k, v := range p.instanceTypeAndRegionToPrice {
+		ondemand[k] = v
+	}
+	return spot, ondemand
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 )
 
 const METADATA_AZ_URL = "http://169.254.169.254/latest/meta-data/placement/availability-zone"
@@ -45,3 +46,8 @@ func getAwsConfig() *aws.Config {
 	}
 	return awsCfg
 }
+
+// NewSession returns a session for the current region, detected by the instance metadata when AWS_REGION is not set
+func NewSession() *session.Session {
+	return session.New(getAwsConfig())
+}
@@ -0,0 +1,108 @@
+package configfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+)
+
+// Version is the only supported version of the configuration file format
+const Version = "v1"
+
+// File is the format of the configuration file, the settings are the flags by their name:
+//
+//	version: v1
+//	flags:
+//	  malus-for-price: 200
+//	  scorer-refresh-interval: 5m
+//	  contexts: [prod, staging]
+type File struct {
+	Version string                 `json:"version"`
+	Flags   map[string]interface{} `json:"flags"`
+}
+
+// Read returns the flags values of the configuration file as strings
+// to be set on the flag set.
+func Read(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	var cf File
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cf); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if cf.Version != Version {
+		return nil, fmt.Errorf("%s: unsupported version %q, it has to be %s", path, cf.Version, Version)
+	}
+	settings := make(map[string]string)
+	for name, value := range cf.Flags {
+		switch v := value.(type) {
+		case string:
+			settings[name] = v
+		case json.Number, bool:
+			settings[name] = fmt.Sprint(v)
+		case []interface{}:
+			items := []string{}
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			settings[name] = strings.Join(items, ",")
+		default:
+			return nil, fmt.Errorf("%s: invalid value for %s", path, name)
+		}
+	}
+	return settings, nil
+}
+
+// Apply sets the flags of the configuration file that were not given as arguments,
+// the notConfigurable flags (and the unknown ones) are rejected.
+func Apply(fs *flag.FlagSet, settings map[string]string, notConfigurable map[string]bool) error {
+	names := []string{}
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil || notConfigurable[name] {
+			return fmt.Errorf("unknown flag %s", name)
+		}
+		if f.Changed {
+			klog.V(2).Infof("Flag %s is set as argument, ignoring the configuration file value", name)
+			continue
+		}
+		if err := fs.Set(name, settings[name]); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Checksums returns the checksum of every file
+func Checksums(paths []string) (map[string]string, error) {
+	checksums := make(map[string]string)
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		checksums[path] = fmt.Sprintf("%x", sha256.Sum256(data))
+	}
+	return checksums, nil
+}
@@ -0,0 +1,131 @@
+package configfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+func writeFile(t *testing.T, dir, content string) string {
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		name     string
+		content  string
+		settings map[string]string
+		wantErr  string
+	}{
+		{
+			name:     "values",
+			content:  "version: v1\nflags:\n  malus-for-price: 200\n  ratio: 1.5\n  ignore-availability-zones: true\n  interval: 5m\n  contexts: [prod, staging]\n",
+			settings: map[string]string{"malus-for-price": "200", "ratio": "1.5", "ignore-availability-zones": "true", "interval": "5m", "contexts": "prod,staging"},
+		},
+		{name: "no flags", content: "version: v1\n", settings: map[string]string{}},
+		{name: "no version", content: "flags:\n  malus-for-price: 200\n", wantErr: `unsupported version ""`},
+		{name: "unknown version", content: "version: v2\nflags:\n  malus-for-price: 200\n", wantErr: `unsupported version "v2"`},
+		{name: "unknown field", content: "version: v1\nsettings:\n  malus-for-price: 200\n", wantErr: "unknown field"},
+		{name: "invalid value", content: "version: v1\nflags:\n  tags:\n    team: batch\n", wantErr: "invalid value for tags"},
+		{name: "invalid YAML", content: "version: [v1\n", wantErr: "config.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := Read(writeFile(t, dir, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Read() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(settings, tt.settings) {
+				t.Errorf("Read() = %v, %v, want %v", settings, err, tt.settings)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		settings map[string]string
+		price    int
+		interval string
+		wantErr  string
+	}{
+		{name: "defaults", price: 100, interval: "1m0s"},
+		{name: "file value over the default", settings: map[string]string{"malus-for-price": "200"}, price: 200, interval: "1m0s"},
+		{
+			name: "explicit flag over the file", args: []string{"--malus-for-price=300"},
+			settings: map[string]string{"malus-for-price": "200", "interval": "5m"}, price: 300, interval: "5m0s",
+		},
+		{
+			name: "explicit flag set to the default", args: []string{"--malus-for-price=100"},
+			settings: map[string]string{"malus-for-price": "200"}, price: 100, interval: "1m0s",
+		},
+		{name: "unknown flag", settings: map[string]string{"malus-for-prize": "200"}, wantErr: "unknown flag malus-for-prize"},
+		{name: "not configurable flag", settings: map[string]string{"config": "other.yaml"}, wantErr: "unknown flag config"},
+		{name: "invalid value", settings: map[string]string{"interval": "often"}, wantErr: "invalid value for interval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			price := fs.Int("malus-for-price", 100, "")
+			fs.String("config", "", "")
+			interval := fs.Duration("interval", time.Minute, "")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := Apply(fs, tt.settings, map[string]bool{"config": true})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Apply() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || *price != tt.price || interval.String() != tt.interval {
+				t.Errorf("Apply() = %d, %s, %v, want %d, %s", *price, interval, err, tt.price, tt.interval)
+			}
+		})
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "version: v1\n")
+	before, err := Checksums([]string{path})
+	if err != nil {
+		t.Fatalf("Checksums() error = %v", err)
+	}
+	path = writeFile(t, dir, "version: v1\nflags:\n  malus-for-price: 200\n")
+	after, err := Checksums([]string{path})
+	if err != nil {
+		t.Fatalf("Checksums() error = %v", err)
+	}
+	if before[path] == "" || before[path] == after[path] {
+		t.Errorf("checksums %q and %q, want two different ones", before[path], after[path])
+	}
+	if _, err := Checksums([]string{filepath.Join(dir, "missing.yaml")}); err == nil {
+		t.Errorf("Checksums() of a missing file didn't fail")
+	}
+}
@@ -0,0 +1,221 @@
+package crd
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer"
+)
+
+// the status is refreshed (when changed) at this interval, besides the resource changes
+const statusInterval = 30 * time.Second
+
+// Controller applies the PriorityHelperConfig named as the target, in the namespace of the target,
+// to the scorer and keeps its status updated. It runs while the scorer is leading, when the
+// resource is missing the scorer goes back to the configuration given at start.
+type Controller struct {
+	client    dynamic.Interface
+	scorer    *scorer.Scorer
+	namespace string
+	name      string
+
+	defaultOutConfigMapName       string
+	defaultBreakdownConfigMapName string
+	defaultRefreshInterval        time.Duration
+
+	mu                 sync.Mutex
+	observedGeneration int64
+	validationErrors   []string
+	// lastStatus is the status written to lastStatusVersion of the resource
+	lastStatus        *Status
+	lastStatusVersion string
+}
+
+var _ scorer.Runnable = &Controller{}
+
+func NewController(client dynamic.Interface, s *scorer.Scorer, namespace string) *Controller {
+	out, breakdown := s.OutputConfigMaps()
+	return &Controller{
+		client:                        client,
+		scorer:                        s,
+		namespace:                     namespace,
+		name:                          s.Target(),
+		defaultOutConfigMapName:       out,
+		defaultBreakdownConfigMapName: breakdown,
+		defaultRefreshInterval:        s.RefreshInterval(),
+	}
+}
+
+func (c *Controller) Start(stopCh <-chan struct{}) error {
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(c.client, 0, c.namespace,
+		func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", c.name).String()
+		})
+	informer := factory.ForResource(Resource)
+	lister := informer.Lister()
+	resync := func() { c.sync(lister) }
+	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { resync() },
+		UpdateFunc: func(_, _ interface{}) { resync() },
+		DeleteFunc: func(interface{}) { resync() },
+	})
+	factory.Start(stopCh)
+
+	// the CRD can be missing, so the scorer doesn't wait for the informer
+	go func() {
+		if !cache.WaitForCacheSync(stopCh, informer.Informer().HasSynced) {
+			return
+		}
+		resync()
+		ticker := time.NewTicker(statusInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				klog.V(1).Infof("%s controller: stopped", Kind)
+				return
+			case <-ticker.C:
+				resync()
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *Controller) sync(lister cache.GenericLister) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	obj, err := lister.ByNamespace(c.namespace).Get(c.name)
+	if errors.IsNotFound(err) {
+		c.reset()
+		return
+	}
+	if err != nil {
+		klog.Errorf("Error getting %s %s/%s: %v", Kind, c.namespace, c.name, err)
+		return
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	c.apply(u)
+	if err := c.updateStatus(u); err != nil {
+		klog.Errorf("Error updating status of %s %s/%s: %v", Kind, c.namespace, c.name, err)
+	}
+}
+
+// apply validates the whole spec before applying it, an invalid one is only reported in the
+// status and the running configuration is left as it is. A generation is applied once.
+func (c *Controller) apply(u *unstructured.Unstructured) {
+	if u.GetGeneration() == c.observedGeneration {
+		return
+	}
+	var spec Spec
+	var validationErrors []string
+	out, breakdown := c.defaultOutConfigMapName, c.defaultBreakdownConfigMapName
+	interval := c.defaultRefreshInterval
+	if err := decodeSpec(u, &spec); err != nil {
+		validationErrors = append(validationErrors, "spec: "+err.Error())
+	} else {
+		if err := c.scorer.ValidateConfigOverlay(spec.Scorer); err != nil {
+			validationErrors = append(validationErrors, "scorer: "+err.Error())
+		}
+		if spec.OutputConfigMap != "" {
+			out = spec.OutputConfigMap
+		}
+		if spec.BreakdownConfigMap != nil {
+			breakdown = *spec.BreakdownConfigMap
+		}
+		if spec.RefreshInterval != nil {
+			interval = spec.RefreshInterval.Duration
+		}
+		if interval <= 0 {
+			validationErrors = append(validationErrors, "refreshInterval: it has to be positive")
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		klog.Errorf("%s %s/%s is not valid, keeping the current configuration: %v", Kind, c.namespace, c.name, validationErrors)
+	} else {
+		if err := c.scorer.SetConfigOverlay(spec.Scorer); err != nil {
+			validationErrors = append(validationErrors, "scorer: "+err.Error())
+		}
+		if err := c.scorer.SetOutputConfigMaps(out, breakdown); err != nil {
+			validationErrors = append(validationErrors, "outputConfigMap: "+err.Error())
+		}
+		if err := c.scorer.SetRefreshInterval(interval); err != nil {
+			validationErrors = append(validationErrors, "refreshInterval: "+err.Error())
+		}
+		klog.Infof("%s %s/%s applied (generation %d)", Kind, c.namespace, c.name, u.GetGeneration())
+	}
+	c.observedGeneration = u.GetGeneration()
+	c.validationErrors = validationErrors
+}
+
+func decodeSpec(u *unstructured.Unstructured, spec *Spec) error {
+	data, err := json.Marshal(u.Object["spec"])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, spec)
+}
+
+// reset restores the configuration given at start
+func (c *Controller) reset() {
+	if c.observedGeneration == 0 {
+		return
+	}
+	klog.Infof("%s %s/%s removed, restoring the configuration", Kind, c.namespace, c.name)
+	if err := c.scorer.SetConfigOverlay(nil); err != nil {
+		klog.Errorf("Error restoring the scorer configuration: %v", err)
+	}
+	if err := c.scorer.SetOutputConfigMaps(c.defaultOutConfigMapName, c.defaultBreakdownConfigMapName); err != nil {
+		klog.Errorf("Error restoring the output ConfigMaps: %v", err)
+	}
+	if err := c.scorer.SetRefreshInterval(c.defaultRefreshInterval); err != nil {
+		klog.Errorf("Error restoring the refresh interval: %v", err)
+	}
+	c.observedGeneration = 0
+	c.validationErrors = nil
+}
+
+func (c *Controller) updateStatus(u *unstructured.Unstructured) error {
+	status := Status{
+		ObservedGeneration: c.observedGeneration,
+		Sources:            c.scorer.SourcesHealth(),
+		ValidationErrors:   c.validationErrors,
+	}
+	if t := c.scorer.LastSuccessfulUpdate(); !t.IsZero() {
+		// the status has the precision of the serialized time
+		lastUpdate := metav1.NewTime(t.Truncate(time.Second))
+		status.LastSuccessfulUpdate = &lastUpdate
+	}
+	// the status is written only when it changed, since the last write of this resource version
+	if c.lastStatus != nil && c.lastStatusVersion == u.GetResourceVersion() && reflect.DeepEqual(*c.lastStatus, status) {
+		return nil
+	}
+	statusObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return err
+	}
+	u = u.DeepCopy()
+	u.Object["status"] = statusObj
+	updated, err := c.client.Resource(Resource).Namespace(c.namespace).UpdateStatus(u, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	c.lastStatus, c.lastStatusVersion = &status, updated.GetResourceVersion()
+	return nil
+}
@@ -0,0 +1,36 @@
+package crd
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer"
+)
+
+const (
+	Group   = "priorityhelper.safanaj.github.io"
+	Version = "v1alpha1"
+	Kind    = "PriorityHelperConfig"
+)
+
+// Resource is the PriorityHelperConfig resource, a namespaced one
+var Resource = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "priorityhelperconfigs"}
+
+// Spec of a PriorityHelperConfig, the fields that are not set keep the helper configuration
+type Spec struct {
+	// Scorer has the fields of the scorer configuration to override (e.g. malusForPrice, ignoreAZs, disabledComponents)
+	Scorer             json.RawMessage  `json:"scorer,omitempty"`
+	OutputConfigMap    string           `json:"outputConfigMap,omitempty"`
+	BreakdownConfigMap *string          `json:"breakdownConfigMap,omitempty"`
+	RefreshInterval    *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// Status of a PriorityHelperConfig, updated by the leading helper
+type Status struct {
+	ObservedGeneration   int64                 `json:"observedGeneration"`
+	LastSuccessfulUpdate *metav1.Time          `json:"lastSuccessfulUpdate,omitempty"`
+	Sources              []scorer.SourceHealth `json:"sources,omitempty"`
+	ValidationErrors     []string              `json:"validationErrors,omitempty"`
+}
@@ -0,0 +1,131 @@
+package extscorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	// FailOpen ignores the external scorer when it fails, the built-in scores are published
+	FailOpen = "open"
+	// FailClosed skips the update of the priorities when the external scorer fails
+	FailClosed = "closed"
+
+	defaultTimeout = 2 * time.Second
+)
+
+// ASG are the details and the computed score components of an ASG sent to the external scorer
+type ASG struct {
+	Name             string            `json:"name"`
+	Pool             string            `json:"pool,omitempty"`
+	InstanceType     string            `json:"instanceType"`
+	InstanceTypes    []string          `json:"instanceTypes"`
+	AvailabilityZone string            `json:"availabilityZone"`
+	IsSpot           bool              `json:"isSpot"`
+	Price            float64           `json:"price"`
+	HasPrice         bool              `json:"hasPrice"`
+	Probability      float64           `json:"probability"`
+	NodeCount        int               `json:"nodeCount"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	Components       map[string]int    `json:"components"`
+	Priority         int               `json:"priority"`
+}
+
+// Request is the JSON body posted to the external scorer, once for every computation
+type Request struct {
+	Time    time.Time `json:"time"`
+	Target  string    `json:"target"`
+	Cluster string    `json:"cluster,omitempty"`
+	ASGs    []ASG     `json:"asgs"`
+}
+
+// Decision is the outcome of the external scorer for an ASG, a vetoed ASG is not published
+type Decision struct {
+	Bonus  int    `json:"bonus"`
+	Malus  int    `json:"malus"`
+	Veto   bool   `json:"veto"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Response is the JSON body expected from the external scorer, the ASGs w/o a decision are untouched
+type Response struct {
+	Decisions map[string]Decision `json:"decisions"`
+}
+
+// Config describes the external scorer endpoint
+type Config struct {
+	URL           string
+	Timeout       time.Duration
+	FailurePolicy string
+	Headers       map[string]string
+}
+
+// Client posts the ASGs to the external scorer
+type Client struct {
+	Config
+	client *http.Client
+}
+
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("the external scorer has no url")
+	}
+	switch cfg.FailurePolicy {
+	case "":
+		cfg.FailurePolicy = FailOpen
+	case FailOpen, FailClosed:
+	default:
+		return nil, fmt.Errorf("the external scorer failure policy has to be %s or %s", FailOpen, FailClosed)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Client{Config: cfg, client: &http.Client{Timeout: cfg.Timeout}}, nil
+}
+
+// FailClosed tells whether the priorities must not be updated when the external scorer fails
+func (c *Client) FailClosed() bool {
+	return c.FailurePolicy == FailClosed
+}
+
+// Score posts the ASGs and returns the decisions by ASG name
+func (c *Client) Score(request Request) (map[string]Decision, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	response := Response{}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	for name, d := range response.Decisions {
+		if d.Bonus < 0 || d.Malus < 0 {
+			return nil, fmt.Errorf("invalid decision for %s: bonus and malus can't be negative", name)
+		}
+	}
+	return response.Decisions, nil
+}
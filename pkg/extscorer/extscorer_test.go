@@ -0,0 +1,156 @@
+package extscorer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, url string, timeout time.Duration) *Client {
+	c, err := NewClient(Config{URL: url, Timeout: timeout, Headers: map[string]string{"Authorization": "Bearer token"}})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
+
+func TestScore(t *testing.T) {
+	var request Request
+	var auth, contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(Response{Decisions: map[string]Decision{
+			"asg-a": {Bonus: 100},
+			"asg-b": {Malus: 50, Veto: true, Reason: "maintenance"},
+		}})
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL, time.Second)
+	decisions, err := c.Score(Request{Target: "default", ASGs: []ASG{{Name: "asg-a"}, {Name: "asg-b"}, {Name: "asg-c"}}})
+	if err != nil {
+		t.Fatalf("Score() error = %v", err)
+	}
+	if auth != "Bearer token" || contentType != "application/json" {
+		t.Errorf("headers Authorization = %q, Content-Type = %q", auth, contentType)
+	}
+	if request.Target != "default" || len(request.ASGs) != 3 {
+		t.Errorf("unexpected request %+v", request)
+	}
+	if len(decisions) != 2 {
+		t.Fatalf("decisions = %v, want 2", decisions)
+	}
+	if d := decisions["asg-a"]; d.Bonus != 100 || d.Veto {
+		t.Errorf("asg-a decision = %+v", d)
+	}
+	if d := decisions["asg-b"]; d.Malus != 50 || !d.Veto || d.Reason != "maintenance" {
+		t.Errorf("asg-b decision = %+v", d)
+	}
+}
+
+func TestScoreErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr string
+	}{
+		{
+			name: "non-2xx status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "boom", http.StatusInternalServerError)
+			},
+			wantErr: "unexpected status",
+		},
+		{
+			name: "malformed response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"decisions": [`))
+			},
+			wantErr: "invalid response",
+		},
+		{
+			name: "wrong response type",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"decisions": {"asg-a": {"bonus": "high"}}}`))
+			},
+			wantErr: "invalid response",
+		},
+		{
+			name: "negative bonus",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"decisions": {"asg-a": {"bonus": -10}}}`))
+			},
+			wantErr: "can't be negative",
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+				w.Write([]byte(`{"decisions": {}}`))
+			},
+			wantErr: "Client.Timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			c := newTestClient(t, srv.URL, 50*time.Millisecond)
+			_, err := c.Score(Request{ASGs: []ASG{{Name: "asg-a"}}})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Score() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewClient(Config{}); err == nil {
+		t.Errorf("NewClient() w/o url succeeded")
+	}
+	if _, err := NewClient(Config{URL: "http://localhost", FailurePolicy: "ignore"}); err == nil {
+		t.Errorf("NewClient() with an unknown failure policy succeeded")
+	}
+	c, err := NewClient(Config{URL: "http://localhost"})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if c.FailClosed() || c.Timeout != defaultTimeout {
+		t.Errorf("defaults: fail closed %v, timeout %s", c.FailClosed(), c.Timeout)
+	}
+	c, err = NewClient(Config{URL: "http://localhost", FailurePolicy: FailClosed})
+	if err != nil || !c.FailClosed() {
+		t.Errorf("NewClient() fail closed: %v, %v", c, err)
+	}
+}
+
+func TestStubHandler(t *testing.T) {
+	rules := []StubRule{
+		{Pattern: "-spot-", Decision: Decision{Bonus: 20}},
+		{Pattern: "^legacy-", Decision: Decision{Veto: true}},
+	}
+	for i := range rules {
+		rules[i].re = regexp.MustCompile(rules[i].Pattern)
+	}
+	srv := httptest.NewServer(StubHandler(rules))
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL, time.Second)
+	decisions, err := c.Score(Request{ASGs: []ASG{{Name: "app-spot-eu-west-1a"}, {Name: "legacy-eu-west-1a"}, {Name: "app-eu-west-1a"}}})
+	if err != nil {
+		t.Fatalf("Score() error = %v", err)
+	}
+	if len(decisions) != 2 || decisions["app-spot-eu-west-1a"].Bonus != 20 || !decisions["legacy-eu-west-1a"].Veto {
+		t.Errorf("unexpected decisions %v", decisions)
+	}
+}
@@ -0,0 +1,68 @@
+package extscorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+)
+
+// StubRule gives the decision for the ASGs whose name matches the pattern
+type StubRule struct {
+	Pattern string `json:"pattern"`
+	Decision
+
+	re *regexp.Regexp
+}
+
+// LoadStubRules reads a YAML file with a list of StubRule
+func LoadStubRules(path string) ([]StubRule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []StubRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		re, err := regexp.Compile(rules[i].Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i, err)
+		}
+		rules[i].re = re
+	}
+	return rules, nil
+}
+
+// StubHandler is a stand-in external scorer answering with the decision of the first
+// rule matching every ASG, to try the integration w/o a real scoring service.
+func StubHandler(rules []StubRule) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		request := Request{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		response := Response{Decisions: make(map[string]Decision)}
+		for _, asg := range request.ASGs {
+			for _, rule := range rules {
+				if rule.re.MatchString(asg.Name) {
+					response.Decisions[asg.Name] = rule.Decision
+					break
+				}
+			}
+		}
+		klog.V(2).Infof("External scorer stub: %d ASGs of target %s, %d decisions", len(request.ASGs), request.Target, len(response.Decisions))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	})
+}
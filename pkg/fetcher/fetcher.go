@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/metrics"
 )
 
 type DataManager struct {
@@ -12,14 +14,30 @@ type DataManager struct {
 	name       string
 	lastChange time.Time
 	checksum   string
+	synced     bool
+	failures   int
 	interval   time.Duration
 	mu         sync.RWMutex
-	stopCh     <-chan struct{}
-	changesCh  chan<- struct{}
+
+	// fetchMu serializes the fetches, a stopped loop can still be fetching when restarted
+	fetchMu sync.Mutex
+
+	// the data source can be shared, it is fetched while there are subscribers
+	startMu     sync.Mutex
+	subsMu      sync.Mutex
+	subscribers map[int]chan<- struct{}
+	nextSubID   int
+	loopStopCh  chan struct{}
+
+	failureHandlers []FailureHandler
 }
 
+// FailureHandler is called after every fetch with the number of consecutive failures
+// (zero when the fetch succeeded) and the last error.
+type FailureHandler func(name string, failures int, err error)
+
 func NewDataManager(fetcher Fetcher, name string, interval time.Duration) *DataManager {
-	return &DataManager{fetcher: fetcher, name: name, interval: interval}
+	return &DataManager{fetcher: fetcher, name: name, interval: interval, subscribers: make(map[int]chan<- struct{})}
 
 	// dm := &DataManager{fetcher: fetcher, name: name, interval: interval, stopCh: stopCh, changesCh: changesCh}
 	// if err := dm.fetch(); err != nil {
@@ -44,20 +62,49 @@ func NewDataManager(fetcher Fetcher, name string, interval time.Duration) *DataM
 	// return dm
 }
 
+// Start subscribes to the data changes until stopCh is closed, the data are fetched
+// periodically while there is at least one subscriber. The first subscriber gets the
+// error of the first fetch, the next ones are notified if the data are already available.
 func (m *DataManager) Start(stopCh <-chan struct{}, changesCh chan<- struct{}) error {
-	m.stopCh = stopCh
-	m.changesCh = changesCh
+	m.startMu.Lock()
+	defer m.startMu.Unlock()
+
+	m.subsMu.Lock()
+	id := m.nextSubID
+	m.nextSubID++
+	m.subscribers[id] = changesCh
+	m.subsMu.Unlock()
+
+	if m.loopStopCh != nil {
+		klog.V(2).Infof("%s: already started, new subscriber %d", m.name, id)
+		go m.unsubscribeOnStop(id, stopCh)
+		if m.HasSynced() {
+			select {
+			case changesCh <- struct{}{}:
+			default:
+			}
+		}
+		return nil
+	}
+
 	if err := m.fetch(); err != nil {
 		klog.Errorf("%s failed: %v", m.name, err)
+		m.subsMu.Lock()
+		delete(m.subscribers, id)
+		m.subsMu.Unlock()
 		return err
 	}
+	loopStopCh := make(chan struct{})
+	m.loopStopCh = loopStopCh
+	go m.unsubscribeOnStop(id, stopCh)
+
 	ticker := time.NewTicker(m.interval)
 	go func() {
 		defer ticker.Stop()
 		for {
 			select {
-			case <-m.stopCh:
-				klog.V(1).Infof("%s: Stopped internal stop channel", m.name)
+			case <-loopStopCh:
+				klog.V(1).Infof("%s: Stopped, no more subscribers", m.name)
 				return
 			case <-ticker.C:
 				if err := m.fetch(); err != nil {
@@ -69,7 +116,53 @@ func (m *DataManager) Start(stopCh <-chan struct{}, changesCh chan<- struct{}) e
 	return nil
 }
 
-func (m *DataManager) fetch() error {
+func (m *DataManager) unsubscribeOnStop(id int, stopCh <-chan struct{}) {
+	<-stopCh
+	m.startMu.Lock()
+	defer m.startMu.Unlock()
+	m.subsMu.Lock()
+	delete(m.subscribers, id)
+	empty := len(m.subscribers) == 0
+	m.subsMu.Unlock()
+	if empty && m.loopStopCh != nil {
+		close(m.loopStopCh)
+		m.loopStopCh = nil
+	}
+}
+
+// notify the subscribers w/o blocking
+func (m *DataManager) notify() {
+	m.subsMu.Lock()
+	defer m.subsMu.Unlock()
+	for id, changesCh := range m.subscribers {
+		select {
+		case changesCh <- struct{}{}:
+			klog.V(2).Infof("%s data changed, subscriber %d notified", m.name, id)
+		default:
+		}
+	}
+}
+
+// AddFailureHandler has to be called before Start, a shared data source can have a handler for every consumer
+func (m *DataManager) AddFailureHandler(handler FailureHandler) {
+	m.failureHandlers = append(m.failureHandlers, handler)
+}
+
+func (m *DataManager) fetch() (err error) {
+	m.fetchMu.Lock()
+	defer m.fetchMu.Unlock()
+	start := time.Now()
+	defer func() {
+		metrics.ObserveFetch(m.name, time.Since(start), err)
+		if err != nil {
+			m.failures++
+		} else {
+			m.failures = 0
+		}
+		for _, handler := range m.failureHandlers {
+			handler(m.name, m.failures, err)
+		}
+	}()
 	data, err := m.fetcher.GetData()
 	if err != nil {
 		return err
@@ -82,21 +175,27 @@ func (m *DataManager) fetch() error {
 	defer m.mu.Unlock()
 	err = m.fetcher.ProcessData(data)
 	if err == nil {
+		m.synced = true
 		m.checksum = checksum
 		m.lastChange = time.Now()
-		klog.V(2).Infof("%s data changed at %s, checksum: %s - channel at %p", m.name, m.lastChange.String(), m.checksum, m.changesCh)
-		// notify for changes w/o blocking
-		select {
-		case m.changesCh <- struct{}{}:
-			klog.V(2).Infof("%s data changed Channel (%p) notified", m.name, m.changesCh)
-		default:
-		}
+		metrics.IncChecksumChanges(m.name)
+		klog.V(2).Infof("%s data changed at %s, checksum: %s", m.name, m.lastChange.String(), m.checksum)
+		m.notify()
 	}
 	return err
 }
 
 func (m *DataManager) GetLastChanges() time.Time { return m.lastChange }
 
+// HasSynced returns true once data were successfully fetched and processed at least once
+func (m *DataManager) HasSynced() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.synced
+}
+
+func (m *DataManager) GetName() string { return m.name }
+
 func (m *DataManager) Lock()    { m.mu.Lock() }
 func (m *DataManager) Unlock()  { m.mu.Unlock() }
 func (m *DataManager) RLock()   { m.mu.RLock() }
@@ -0,0 +1,52 @@
+package history
+
+import (
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer"
+)
+
+// Recorder periodically stores the scorer snapshots, to build the history used by the backtest
+type Recorder struct {
+	store    *Store
+	interval time.Duration
+	scorer   *scorer.Scorer
+}
+
+var _ scorer.Runnable = &Recorder{}
+
+func NewRecorder(store *Store, interval time.Duration, s *scorer.Scorer) *Recorder {
+	return &Recorder{store: store, interval: interval, scorer: s}
+}
+
+func (r *Recorder) record() {
+	snap := r.scorer.TakeSnapshot()
+	if len(snap.ASGs) == 0 {
+		klog.V(2).Infof("Recorder: skipping empty snapshot")
+		return
+	}
+	if err := r.store.Append(snap); err != nil {
+		klog.Errorf("Recorder: error storing snapshot: %v", err)
+		return
+	}
+	klog.V(3).Infof("Recorder: stored snapshot taken at %s", snap.Time)
+}
+
+func (r *Recorder) Start(stopCh <-chan struct{}) error {
+	ticker := time.NewTicker(r.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				klog.V(1).Infof("Recorder: stopped")
+				return
+			case <-ticker.C:
+				r.record()
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,177 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer"
+)
+
+const (
+	filePrefix = "snapshots-"
+	fileSuffix = ".jsonl"
+	dayLayout  = "2006-01-02"
+)
+
+// Store keeps the snapshots in a local directory, one JSON document per line
+// and one file per (UTC) day.
+type Store struct {
+	mu  sync.Mutex
+	dir string
+	// maxAge and maxSize limit the kept files, see SetRetention
+	maxAge  time.Duration
+	maxSize int64
+}
+
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// SetRetention limits the history: the files of the days older than maxAge are removed and then
+// the oldest ones until the total size is below maxSize (the current day is always kept), 0 is no limit.
+func (st *Store) SetRetention(maxAge time.Duration, maxSize int64) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.maxAge, st.maxSize = maxAge, maxSize
+}
+
+func (st *Store) fileFor(t time.Time) string {
+	return filepath.Join(st.dir, fmt.Sprintf("%s%s%s", filePrefix, t.UTC().Format(dayLayout), fileSuffix))
+}
+
+func (st *Store) Append(snap *scorer.Snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	f, err := os.OpenFile(st.fileFor(snap.Time), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return st.prune(snap.Time)
+}
+
+// prune removes the files exceeding the retention, it has to be called with the lock held
+func (st *Store) prune(now time.Time) error {
+	if st.maxAge == 0 && st.maxSize == 0 {
+		return nil
+	}
+	files, err := st.snapshotFiles()
+	if err != nil {
+		return err
+	}
+	today := now.UTC().Format(dayLayout)
+	oldest := now.Add(-st.maxAge).UTC().Format(dayLayout)
+	var total int64
+	for _, fi := range files {
+		total += fi.Size()
+	}
+	// the files are sorted by day, the oldest first
+	for _, fi := range files {
+		day := dayOf(fi.Name())
+		if day == today {
+			break
+		}
+		if (st.maxAge == 0 || day >= oldest) && (st.maxSize == 0 || total <= st.maxSize) {
+			break
+		}
+		if err := os.Remove(filepath.Join(st.dir, fi.Name())); err != nil {
+			return err
+		}
+		total -= fi.Size()
+		klog.V(2).Infof("Removed snapshots file %s exceeding the history retention", fi.Name())
+	}
+	return nil
+}
+
+// snapshotFiles returns the files of the snapshots sorted by day
+func (st *Store) snapshotFiles() ([]os.FileInfo, error) {
+	files, err := ioutil.ReadDir(st.dir)
+	if err != nil {
+		return nil, err
+	}
+	res := []os.FileInfo{}
+	for _, fi := range files {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		res = append(res, fi)
+	}
+	// ReadDir sorts by name, that is by day
+	return res, nil
+}
+
+func dayOf(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix)
+}
+
+// Load returns the snapshots taken in the [from, to] period sorted by time
+func (st *Store) Load(from, to time.Time) ([]*scorer.Snapshot, error) {
+	files, err := st.snapshotFiles()
+	if err != nil {
+		return nil, err
+	}
+	fromDay := from.UTC().Format(dayLayout)
+	toDay := to.UTC().Format(dayLayout)
+
+	snaps := []*scorer.Snapshot{}
+	for _, fi := range files {
+		name := fi.Name()
+		day := dayOf(name)
+		if day < fromDay || day > toDay {
+			continue
+		}
+		if err := st.loadFile(filepath.Join(st.dir, name), func(snap *scorer.Snapshot) {
+			if !snap.Time.Before(from) && !snap.Time.After(to) {
+				snaps = append(snaps, snap)
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Time.Before(snaps[j].Time) })
+	return snaps, nil
+}
+
+func (st *Store) loadFile(path string, fn func(*scorer.Snapshot)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	// a snapshot can be big, it depends on the number of ASGs
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		snap := &scorer.Snapshot{}
+		if err := json.Unmarshal(scanner.Bytes(), snap); err != nil {
+			klog.Warningf("Skipping invalid snapshot in %s: %v", path, err)
+			continue
+		}
+		fn(snap)
+	}
+	return scanner.Err()
+}
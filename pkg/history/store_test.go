@@ -0,0 +1,69 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2020, 4, 10, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		maxSize int64
+		days    []string
+	}{
+		{name: "no retention", days: []string{"2020-04-06", "2020-04-07", "2020-04-08", "2020-04-09", "2020-04-10"}},
+		{name: "max age", maxAge: 2 * day, days: []string{"2020-04-08", "2020-04-09", "2020-04-10"}},
+		{name: "max size", maxSize: 250, days: []string{"2020-04-09", "2020-04-10"}},
+		{name: "max size at the limit", maxSize: 300, days: []string{"2020-04-08", "2020-04-09", "2020-04-10"}},
+		{name: "max age and size", maxAge: 3 * day, maxSize: 350, days: []string{"2020-04-08", "2020-04-09", "2020-04-10"}},
+		{name: "today is always kept", maxSize: 1, days: []string{"2020-04-10"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "history")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			st, err := NewStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 100 bytes a day for the last 5 days, and a file that is not of the store
+			for i := 4; i >= 0; i-- {
+				if err := ioutil.WriteFile(st.fileFor(now.Add(-time.Duration(i)*day)), make([]byte, 100), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, "notes.txt"), make([]byte, 1000), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			st.SetRetention(tt.maxAge, tt.maxSize)
+			if err := st.prune(now); err != nil {
+				t.Fatalf("prune() error = %v", err)
+			}
+			files, err := st.snapshotFiles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			days := []string{}
+			for _, fi := range files {
+				days = append(days, dayOf(fi.Name()))
+			}
+			if !reflect.DeepEqual(days, tt.days) {
+				t.Errorf("days = %v, want %v", days, tt.days)
+			}
+			if _, err := os.Stat(dir + "/notes.txt"); err != nil {
+				t.Errorf("the file not of the store was removed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/aws"
+)
+
+// rules checked against every discovered ASG
+const (
+	RuleMultiAZ             = "multi-az"
+	RuleMissingInstanceType = "missing-instance-type"
+	RuleAllocationStrategy  = "allocation-strategy"
+	RuleSpotDetection       = "spot-detection"
+	RuleIgnoreAZsName       = "ignore-azs-name"
+)
+
+const capacityOptimized = "capacity-optimized"
+
+// Violation is an ASG not following one of the conventions the helper relies on
+type Violation struct {
+	ASGName string `json:"asgName"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: [%s] %s", v.ASGName, v.Rule, v.Message)
+}
+
+// isSpot mirrors the discovery: for MixedInstancesPolicy the convention is to have
+// 0 as OnDemandPercentageAboveBaseCapacity, otherwise the launch template or configuration tells it
+func isSpot(info aws.ASGInfo) bool {
+	if info.MixedInstancesPolicy && info.OnDemandPercentageAboveBaseCapacity != nil {
+		return *info.OnDemandPercentageAboveBaseCapacity == 0
+	}
+	return info.IsSpot
+}
+
+func checkASG(info aws.ASGInfo, ignoreAZs bool) []Violation {
+	res := []Violation{}
+	add := func(rule, format string, args ...interface{}) {
+		res = append(res, Violation{ASGName: info.Name, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(info.AvailabilityZones) != 1 {
+		add(RuleMultiAZ, "it has %d availability zones (%s), the ASGs have to be single AZ and only the first one is considered",
+			len(info.AvailabilityZones), strings.Join(info.AvailabilityZones, ", "))
+	}
+
+	if info.InstanceTypeError != "" {
+		source := "launch template " + info.LaunchTemplateName
+		if info.LaunchConfigurationName != "" {
+			source = "launch configuration " + info.LaunchConfigurationName
+		}
+		add(RuleMissingInstanceType, "can't get the instance type from the %s, the discovery of all the ASGs fails: %s",
+			source, info.InstanceTypeError)
+	}
+
+	if info.MixedInstancesPolicy {
+		odp := info.OnDemandPercentageAboveBaseCapacity
+		switch {
+		case odp == nil:
+			add(RuleSpotDetection, "OnDemandPercentageAboveBaseCapacity is not set, the market is taken from the launch template")
+		case *odp != 0 && *odp != 100:
+			add(RuleSpotDetection, "OnDemandPercentageAboveBaseCapacity is %d, it has to be 0 (spot) or 100 (on-demand), the ASG is considered on-demand", *odp)
+		}
+		if len(info.OverridesInstanceTypes) > 1 && isSpot(info) &&
+			info.SpotAllocationStrategy != capacityOptimized {
+			add(RuleAllocationStrategy, "spot allocation strategy is %q, mixed instance types ASGs have to use %q",
+				info.SpotAllocationStrategy, capacityOptimized)
+		}
+	}
+
+	if ignoreAZs {
+		hasSpotInName := strings.Contains(info.Name, "-spot-")
+		if spot := isSpot(info); spot != hasSpotInName {
+			if spot {
+				add(RuleIgnoreAZsName, "it is a spot ASG but its name doesn't contain \"-spot-\", it will not be grouped ignoring the AZs")
+			} else {
+				add(RuleIgnoreAZsName, "it is an on-demand ASG but its name contains \"-spot-\", it will be grouped as spot ignoring the AZs")
+			}
+		}
+		if hasSpotInName && len(info.AvailabilityZones) > 0 {
+			az := info.AvailabilityZones[0]
+			if !strings.HasSuffix(info.Name, az[len(az)-1:]) {
+				add(RuleIgnoreAZsName, "its name doesn't end with the AZ letter of %s, ignoring the AZs would trim a wrong character", az)
+			}
+		}
+	}
+	return res
+}
+
+// Check validates the ASGs against the conventions, ignoreAZs enables the checks
+// of the names used to group the spot ASGs ignoring the availability zones.
+func Check(infos []aws.ASGInfo, ignoreAZs bool) []Violation {
+	res := []Violation{}
+	for _, info := range infos {
+		res = append(res, checkASG(info, ignoreAZs)...)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].ASGName < res[j].ASGName
+	})
+	return res
+}
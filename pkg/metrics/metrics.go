@@ -0,0 +1,252 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ca_priority_helper"
+
+var (
+	asgPriority = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "asg_priority",
+		Help:      "Computed priority for the ASG.",
+	}, []string{"target", "asg"})
+
+	asgScoreComponent = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "asg_score_component",
+		Help:      "Points of every score component for the ASG, maluses are negative.",
+	}, []string{"target", "asg", "component"})
+
+	sourceFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "source_fetch_duration_seconds",
+		Help:      "Duration of the data source fetches.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+	}, []string{"source"})
+
+	sourceFetchErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "source_fetch_errors_total",
+		Help:      "Number of failed data source fetches.",
+	}, []string{"source"})
+
+	sourceLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "source_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful data source fetch.",
+	}, []string{"source"})
+
+	sourceChecksumChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "source_checksum_changes_total",
+		Help:      "Number of times the data source data changed.",
+	}, []string{"source"})
+
+	configMapUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "configmap_updates_total",
+		Help:      "Number of output ConfigMap updates by result (created, updated, skipped_checksum, skipped_no_data, skipped_external_scorer, error).",
+	}, []string{"configmap", "result"})
+
+	leader = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "1 when this instance is leading and computing priorities for the target.",
+	}, []string{"target"})
+
+	spotPrice = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "spot_price",
+		Help:      "Spot price used for the instance type in the availability zone.",
+	}, []string{"target", "instance_type", "availability_zone"})
+
+	onDemandPrice = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ondemand_price",
+		Help:      "On-demand price used for the instance type in the availability zone.",
+	}, []string{"target", "instance_type", "availability_zone"})
+
+	advisorProbability = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "spot_advisor_probability",
+		Help:      "Spot advisor interruption probability index (0-4) used for the instance type in the region.",
+	}, []string{"target", "instance_type", "region"})
+
+	conventionViolations = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "asg_convention_violations",
+		Help:      "Number of violations of the ASG conventions by ASG and rule, from the last periodic check.",
+	}, []string{"target", "asg", "rule"})
+
+	configReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Number of configuration file reloads by result (applied, error).",
+	}, []string{"result"})
+
+	spotPriceRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "spot_price_ondemand_ratio",
+		Help:      "Ratio between the spot and the on-demand price of the instance type of the spot ASG.",
+	}, []string{"target", "asg"})
+
+	expensiveSpot = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "spot_price_above_threshold",
+		Help:      "1 when the spot price of the spot ASG is above the configured ratio of the on-demand price.",
+	}, []string{"target", "asg"})
+
+	externalScorerRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "external_scorer_requests_total",
+		Help:      "Number of requests to the external scorer by result (ok, error).",
+	}, []string{"target", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		asgPriority,
+		asgScoreComponent,
+		sourceFetchDuration,
+		sourceFetchErrors,
+		sourceLastSuccess,
+		sourceChecksumChanges,
+		configMapUpdates,
+		leader,
+		spotPrice,
+		onDemandPrice,
+		advisorProbability,
+		conventionViolations,
+		configReloads,
+		externalScorerRequests,
+		spotPriceRatio,
+		expensiveSpot,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// series set for every target, so that the ones of a target can be dropped w/o touching the others
+type series struct {
+	vec    *prometheus.GaugeVec
+	labels []string
+}
+
+var (
+	targetSeriesMu sync.Mutex
+	targetSeries   = make(map[string][]series)
+)
+
+func setTargetGauge(vec *prometheus.GaugeVec, target string, value float64, labels ...string) {
+	labels = append([]string{target}, labels...)
+	vec.WithLabelValues(labels...).Set(value)
+	targetSeriesMu.Lock()
+	defer targetSeriesMu.Unlock()
+	targetSeries[target] = append(targetSeries[target], series{vec: vec, labels: labels})
+}
+
+func resetTargetGauges(target string, vecs ...*prometheus.GaugeVec) {
+	targetSeriesMu.Lock()
+	defer targetSeriesMu.Unlock()
+	keep := []series{}
+	for _, s := range targetSeries[target] {
+		reset := false
+		for _, vec := range vecs {
+			if s.vec == vec {
+				reset = true
+				break
+			}
+		}
+		if reset {
+			s.vec.DeleteLabelValues(s.labels...)
+		} else {
+			keep = append(keep, s)
+		}
+	}
+	targetSeries[target] = keep
+}
+
+// ResetScores drops the per ASG and per instance type series of the target, to be called before
+// setting the new ones so that ASGs or instance types that disappeared are not reported anymore.
+func ResetScores(target string) {
+	resetTargetGauges(target, asgPriority, asgScoreComponent, spotPrice, onDemandPrice, advisorProbability, spotPriceRatio, expensiveSpot)
+}
+
+func SetASGScore(target, asg string, priority int, components map[string]int) {
+	setTargetGauge(asgPriority, target, float64(priority), asg)
+	for component, points := range components {
+		setTargetGauge(asgScoreComponent, target, float64(points), asg, component)
+	}
+}
+
+func SetSpotPrice(target, instanceType, az string, price float64) {
+	setTargetGauge(spotPrice, target, price, instanceType, az)
+}
+
+func SetOnDemandPrice(target, instanceType, az string, price float64) {
+	setTargetGauge(onDemandPrice, target, price, instanceType, az)
+}
+
+func SetAdvisorProbability(target, instanceType, region string, probability int) {
+	setTargetGauge(advisorProbability, target, float64(probability), instanceType, region)
+}
+
+// SetSpotPriceRatio sets the spot to on-demand price ratio of a spot ASG and whether it is above the threshold
+func SetSpotPriceRatio(target, asg string, ratio float64, aboveThreshold bool) {
+	setTargetGauge(spotPriceRatio, target, ratio, asg)
+	above := 0.0
+	if aboveThreshold {
+		above = 1
+	}
+	setTargetGauge(expensiveSpot, target, above, asg)
+}
+
+func ObserveFetch(source string, duration time.Duration, err error) {
+	sourceFetchDuration.WithLabelValues(source).Observe(duration.Seconds())
+	if err != nil {
+		sourceFetchErrors.WithLabelValues(source).Inc()
+		return
+	}
+	sourceLastSuccess.WithLabelValues(source).Set(float64(time.Now().Unix()))
+}
+
+func IncChecksumChanges(source string) {
+	sourceChecksumChanges.WithLabelValues(source).Inc()
+}
+
+func IncConfigMapUpdates(configMap, result string) {
+	configMapUpdates.WithLabelValues(configMap, result).Inc()
+}
+
+func IncConfigReloads(result string) {
+	configReloads.WithLabelValues(result).Inc()
+}
+
+func IncExternalScorerRequests(target, result string) {
+	externalScorerRequests.WithLabelValues(target, result).Inc()
+}
+
+func SetLeader(target string, isLeader bool) {
+	if isLeader {
+		leader.WithLabelValues(target).Set(1)
+	} else {
+		leader.WithLabelValues(target).Set(0)
+	}
+}
+
+// SetConventionViolations replaces the series of the ASG conventions violations of the target, counted by ASG and rule
+func SetConventionViolations(target string, counts map[[2]string]int) {
+	resetTargetGauges(target, conventionViolations)
+	for k, count := range counts {
+		setTargetGauge(conventionViolations, target, float64(count), k[0], k[1])
+	}
+}
@@ -0,0 +1,78 @@
+package nodes
+
+import (
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/utils"
+)
+
+// Counts maps the InstanceDetails string representation (instance type, AZ and market)
+// to the number of nodes running with those details.
+type Counts map[string]int
+
+func (c Counts) Copy() Counts {
+	res := make(Counts, len(c))
+	for k, v := range c {
+		res[k] = v
+	}
+	return res
+}
+
+func (c Counts) GetCountFor(args ...string) (count int) {
+	argslen := len(args)
+	if argslen < 2 {
+		return
+	}
+	instanceType := args[0]
+	az := args[1]
+	if argslen == 2 || (argslen > 2 && args[2] == "spot") {
+		if countSpot, ok := c[instanceTypeAZKeyFunc(instanceType, az, true)]; ok {
+			count += countSpot
+		}
+	}
+	if argslen == 2 || (argslen > 2 && args[2] != "spot") {
+		if countOnDemand, ok := c[instanceTypeAZKeyFunc(instanceType, az, false)]; ok {
+			count += countOnDemand
+		}
+	}
+	return
+}
+
+func (c Counts) GetCountForInstanceType(args ...string) (count int) {
+	argslen := len(args)
+	if argslen < 1 {
+		return
+	}
+	instanceType := args[0]
+
+	for k, v := range c {
+		iDetails := utils.InstanceDetails{}
+		(&iDetails).FromString(k)
+		if iDetails.InstanceType == instanceType {
+			if argslen > 1 {
+				if args[1] == "spot" {
+					if iDetails.IsSpot {
+						count += v
+					}
+				} else {
+					if !iDetails.IsSpot {
+						count += v
+					}
+				}
+			} else {
+				count += v
+			}
+		}
+
+	}
+	return
+}
+
+func (c Counts) GetCountForAZ(az string) (count int) {
+	for k, v := range c {
+		iDetails := utils.InstanceDetails{}
+		(&iDetails).FromString(k)
+		if iDetails.AvailabilityZone == az {
+			count += v
+		}
+	}
+	return
+}
@@ -3,9 +3,12 @@ package nodes
 import (
 	"fmt"
 	// "strings"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
 	listers_v1 "k8s.io/client-go/listers/core/v1"
@@ -24,7 +27,7 @@ const (
 )
 
 func instanceTypeAZKeyFunc(instanceType, az string, isSpot bool) string {
-	return (utils.InstanceDetails{instanceType, az, isSpot}).String()
+	return (utils.InstanceDetails{InstanceType: instanceType, AvailabilityZone: az, IsSpot: isSpot}).String()
 }
 
 func instanceTypeAZKeyFromNode(node *corev1.Node) (string, bool) {
@@ -44,38 +47,92 @@ func instanceTypeAZKeyFromNode(node *corev1.Node) (string, bool) {
 	return instanceTypeAZKeyFunc(iType, az, isSpot), true
 }
 
+func isMaster(node *corev1.Node) bool {
+	return node.ObjectMeta.Labels["kubernetes.io/role"] == "master"
+}
+
 type nodesData struct {
-	instanceTypeAZCount map[string]int
+	instanceTypeAZCount Counts
 }
 
 type NodesDistribution struct {
+	mu           sync.RWMutex
 	data         nodesData
 	cs           clientset.Interface
 	factory      informers.SharedInformerFactory
 	nodeInformer cache.SharedIndexInformer
 	nodeLister   listers_v1.NodeLister
 	lastChange   time.Time
+
+	// the informer is shared by all the subscribers, once started it keeps running
+	startMu     sync.Mutex
+	started     bool
+	subsMu      sync.Mutex
+	subscribers map[int]chan<- struct{}
+	nextSubID   int
 }
 
 func NewNodesDistribution(clientset clientset.Interface) (*NodesDistribution, error) {
 	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0)
 
 	nodes := &NodesDistribution{
-		data:         nodesData{instanceTypeAZCount: make(map[string]int)},
+		data:         nodesData{instanceTypeAZCount: make(Counts)},
 		cs:           clientset,
 		factory:      factory,
 		nodeInformer: factory.Core().V1().Nodes().Informer(),
 		nodeLister:   factory.Core().V1().Nodes().Lister(),
+		subscribers:  make(map[int]chan<- struct{}),
 	}
 
 	return nodes, nil
 }
 
+// notify the subscribers w/o blocking
+func (n *NodesDistribution) notify() {
+	n.subsMu.Lock()
+	defer n.subsMu.Unlock()
+	for _, changesCh := range n.subscribers {
+		select {
+		case changesCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start subscribes to the nodes distribution changes until stopCh is closed,
+// the first call starts the nodes informer.
 func (n *NodesDistribution) Start(stopCh <-chan struct{}, changesCh chan<- struct{}) error {
+	n.subsMu.Lock()
+	id := n.nextSubID
+	n.nextSubID++
+	n.subscribers[id] = changesCh
+	n.subsMu.Unlock()
+	go func() {
+		<-stopCh
+		n.subsMu.Lock()
+		delete(n.subscribers, id)
+		n.subsMu.Unlock()
+	}()
+
+	n.startMu.Lock()
+	defer n.startMu.Unlock()
+	if !n.started {
+		n.startInformer()
+		n.started = true
+	}
+	for _, ok := range n.factory.WaitForCacheSync(stopCh) {
+		if !ok {
+			return fmt.Errorf("node informer did not sync")
+		}
+	}
+	return nil
+}
+
+func (n *NodesDistribution) startInformer() {
 	nodeEventHandler := cache.FilteringResourceEventHandler{
 		FilterFunc: func(obj interface{}) bool {
 			if node, ok := obj.(*corev1.Node); ok {
-				return node.ObjectMeta.Labels["kubernetes.io/role"] != "master"
+				return !isMaster(node)
 			}
 			return false
 		},
@@ -83,110 +140,83 @@ func (n *NodesDistribution) Start(stopCh <-chan struct{}, changesCh chan<- struc
 			AddFunc: func(obj interface{}) {
 				if node, ok := obj.(*corev1.Node); ok {
 					if k, ok := instanceTypeAZKeyFromNode(node); ok {
+						n.mu.Lock()
 						if _, ok := n.data.instanceTypeAZCount[k]; ok {
 							n.data.instanceTypeAZCount[k]++
 						} else {
 							n.data.instanceTypeAZCount[k] = 1
 						}
+						n.mu.Unlock()
 						n.lastChange = time.Now()
 						klog.V(2).Infof("Nodes distribution changed at %s", n.lastChange.String())
-						// notify for changes w/o blocking
-						select {
-						case changesCh <- struct{}{}:
-						default:
-						}
+						n.notify()
 					}
 				}
 			},
 			DeleteFunc: func(obj interface{}) {
 				if node, ok := obj.(*corev1.Node); ok {
 					if k, ok := instanceTypeAZKeyFromNode(node); ok {
+						n.mu.Lock()
 						if _, ok := n.data.instanceTypeAZCount[k]; ok {
 							n.data.instanceTypeAZCount[k]--
 						}
+						n.mu.Unlock()
 						n.lastChange = time.Now()
 						klog.V(2).Infof("Nodes distribution changed at %s", n.lastChange.String())
-						// notify for changes w/o blocking
-						select {
-						case changesCh <- struct{}{}:
-						default:
-						}
+						n.notify()
 					}
 				}
 			},
 		},
 	}
 	n.nodeInformer.AddEventHandler(nodeEventHandler)
-	n.factory.Start(stopCh)
-	for _, ok := range n.factory.WaitForCacheSync(stopCh) {
-		if !ok {
-			return fmt.Errorf("node informer did not sync")
-		}
-	}
-	return nil
+	n.factory.Start(wait.NeverStop)
 }
 
-func (n *NodesDistribution) GetCountFor(args ...string) (count int) {
-	argslen := len(args)
-	if argslen < 2 {
-		return
-	}
-	instanceType := args[0]
-	az := args[1]
-	if argslen == 2 || (argslen > 2 && args[2] == "spot") {
-		if countSpot, ok := n.data.instanceTypeAZCount[instanceTypeAZKeyFunc(instanceType, az, true)]; ok {
-			count += countSpot
-		}
-	}
-	if argslen == 2 || (argslen > 2 && args[2] != "spot") {
-		if countOnDemand, ok := n.data.instanceTypeAZCount[instanceTypeAZKeyFunc(instanceType, az, false)]; ok {
-			count += countOnDemand
-		}
-	}
-	return
+func (n *NodesDistribution) GetCountFor(args ...string) int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.data.instanceTypeAZCount.GetCountFor(args...)
 }
 
-func (n *NodesDistribution) GetCountForInstanceType(args ...string) (count int) {
-	argslen := len(args)
-	if argslen < 1 {
-		return
-	}
-	instanceType := args[0]
-
-	for k, v := range n.data.instanceTypeAZCount {
-		iDetails := utils.InstanceDetails{}
-		(&iDetails).FromString(k)
-		if iDetails.InstanceType == instanceType {
-			if argslen > 1 {
-				if args[1] == "spot" {
-					if iDetails.IsSpot {
-						count += v
-					}
-				} else {
-					if !iDetails.IsSpot {
-						count += v
-					}
-				}
-			} else {
-				count += v
-			}
-		}
+func (n *NodesDistribution) GetCountForInstanceType(args ...string) int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.data.instanceTypeAZCount.GetCountForInstanceType(args...)
+}
 
-	}
-	return
+func (n *NodesDistribution) GetCountForAZ(az string) int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.data.instanceTypeAZCount.GetCountForAZ(az)
+}
+
+func (n *NodesDistribution) GetData() map[string]int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.data.instanceTypeAZCount.Copy()
 }
 
-func (n *NodesDistribution) GetCountForAZ(az string) (count int) {
-	for k, v := range n.data.instanceTypeAZCount {
-		iDetails := utils.InstanceDetails{}
-		(&iDetails).FromString(k)
-		if iDetails.AvailabilityZone == az {
-			count += v
+// GetDataFor returns the distribution of the nodes matching the label selector
+func (n *NodesDistribution) GetDataFor(selector labels.Selector) map[string]int {
+	res := make(Counts)
+	nodes, err := n.nodeLister.List(selector)
+	if err != nil {
+		klog.Errorf("Error listing nodes by %s: %v", selector, err)
+		return res
+	}
+	for _, node := range nodes {
+		if isMaster(node) {
+			continue
+		}
+		if k, ok := instanceTypeAZKeyFromNode(node); ok {
+			res[k]++
 		}
 	}
-	return
+	return res
 }
 
-func (n *NodesDistribution) GetData() map[string]int {
-	return n.data.instanceTypeAZCount
+// HasSynced returns true when the nodes informer is up and synced
+func (n *NodesDistribution) HasSynced() bool {
+	return n.nodeInformer.HasSynced()
 }
@@ -0,0 +1,224 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"k8s.io/klog"
+)
+
+const (
+	SignatureHeader = "X-Priority-Helper-Signature"
+
+	defaultTimeout = 10 * time.Second
+	defaultRetries = 3
+)
+
+// retryBackoff is multiplied by the attempt number between the retries
+var retryBackoff = 2 * time.Second
+
+// Notification is the payload sent to the webhooks when the priorities change
+type Notification struct {
+	Time            time.Time        `json:"time"`
+	Target          string           `json:"target"`
+	Cluster         string           `json:"cluster,omitempty"`
+	Namespace       string           `json:"namespace"`
+	ConfigMap       string           `json:"configMap"`
+	Cause           string           `json:"cause"`
+	OldPriorities   map[int][]string `json:"oldPriorities"`
+	NewPriorities   map[int][]string `json:"newPriorities"`
+	OldTopPriority  int              `json:"oldTopPriority"`
+	OldTopASGs      []string         `json:"oldTopASGs"`
+	TopPriority     int              `json:"topPriority"`
+	TopASGs         []string         `json:"topASGs"`
+	Degraded        bool             `json:"degraded"`
+	DegradedSources []string         `json:"degradedSources,omitempty"`
+}
+
+// WebhookConfig describes a webhook, Template is a text/template executed with the Notification
+// (the JSON encoded Notification is sent when empty), the body is signed with HMAC-SHA256
+// when a secret is configured, directly or by an environment variable.
+type WebhookConfig struct {
+	Name          string            `yaml:"name"`
+	URL           string            `yaml:"url"`
+	Template      string            `yaml:"template"`
+	ContentType   string            `yaml:"contentType"`
+	Headers       map[string]string `yaml:"headers"`
+	Secret        string            `yaml:"secret"`
+	SecretFromEnv string            `yaml:"secretFromEnv"`
+	Retries       *int              `yaml:"retries"`
+	Timeout       time.Duration     `yaml:"timeout"`
+}
+
+type webhook struct {
+	WebhookConfig
+	tmpl   *template.Template
+	secret []byte
+	client *http.Client
+}
+
+type Notifier struct {
+	webhooks []*webhook
+}
+
+// LoadWebhooksConfig reads a YAML file containing a list of WebhookConfig
+func LoadWebhooksConfig(path string) ([]WebhookConfig, error) {
+	var configs []WebhookConfig
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+func NewNotifier(configs []WebhookConfig) (*Notifier, error) {
+	n := &Notifier{}
+	for i, cfg := range configs {
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("webhook %d (%s) has no url", i, cfg.Name)
+		}
+		if cfg.Name == "" {
+			cfg.Name = cfg.URL
+		}
+		if cfg.Retries == nil {
+			retries := defaultRetries
+			cfg.Retries = &retries
+		}
+		if cfg.Timeout == 0 {
+			cfg.Timeout = defaultTimeout
+		}
+		if cfg.ContentType == "" {
+			cfg.ContentType = "application/json"
+		}
+		wh := &webhook{WebhookConfig: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+		if cfg.Template != "" {
+			tmpl, err := template.New(cfg.Name).Funcs(template.FuncMap{"json": toJSON}).Parse(cfg.Template)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %s has an invalid template: %v", cfg.Name, err)
+			}
+			wh.tmpl = tmpl
+		}
+		switch {
+		case cfg.Secret != "":
+			wh.secret = []byte(cfg.Secret)
+		case cfg.SecretFromEnv != "":
+			secret, ok := os.LookupEnv(cfg.SecretFromEnv)
+			if !ok {
+				return nil, fmt.Errorf("webhook %s secret environment variable %s is not set", cfg.Name, cfg.SecretFromEnv)
+			}
+			wh.secret = []byte(secret)
+		}
+		n.webhooks = append(n.webhooks, wh)
+	}
+	return n, nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// Sign returns the value of the signature header for the body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return fmt.Sprintf("sha256=%s", hex.EncodeToString(mac.Sum(nil)))
+}
+
+func (wh *webhook) body(notification Notification) ([]byte, error) {
+	if wh.tmpl == nil {
+		return json.Marshal(notification)
+	}
+	buf := &bytes.Buffer{}
+	if err := wh.tmpl.Execute(buf, notification); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (wh *webhook) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", wh.ContentType)
+	for k, v := range wh.Headers {
+		req.Header.Set(k, v)
+	}
+	if wh.secret != nil {
+		req.Header.Set(SignatureHeader, Sign(wh.secret, body))
+	}
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode, status: resp.Status}
+	}
+	return nil
+}
+
+// statusError is a non-2xx response of a webhook
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %s", e.status)
+}
+
+// retryable tells whether a failed post can succeed if retried: the network errors,
+// the server errors and the throttled requests are, the other responses aren't.
+func retryable(err error) bool {
+	if se, ok := err.(*statusError); ok {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func (wh *webhook) send(notification Notification) error {
+	body, err := wh.body(notification)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		if err = wh.post(body); err == nil {
+			return nil
+		}
+		if attempt >= *wh.Retries || !retryable(err) {
+			return err
+		}
+		klog.V(2).Infof("Webhook %s failed (attempt %d): %v, retrying", wh.Name, attempt+1, err)
+		time.Sleep(retryBackoff * time.Duration(attempt+1))
+	}
+}
+
+// Notify sends the notification to all the webhooks w/o blocking the caller
+func (n *Notifier) Notify(notification Notification) {
+	for _, wh := range n.webhooks {
+		go func(wh *webhook) {
+			if err := wh.send(notification); err != nil {
+				klog.Errorf("Webhook %s failed: %v", wh.Name, err)
+				return
+			}
+			klog.V(2).Infof("Webhook %s notified", wh.Name)
+		}(wh)
+	}
+}
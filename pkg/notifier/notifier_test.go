@@ -0,0 +1,213 @@
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	retryBackoff = time.Millisecond
+}
+
+// newWebhook returns the single webhook of a notifier posting to the url
+func newWebhook(t *testing.T, cfg WebhookConfig) *webhook {
+	n, err := NewNotifier([]WebhookConfig{cfg})
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	return n.webhooks[0]
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestSendRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+		retries  int
+		attempts int32
+		wantErr  bool
+	}{
+		{name: "first attempt", failures: 0, retries: 3, attempts: 1},
+		{name: "recovered", failures: 2, retries: 3, attempts: 3},
+		{name: "retries exhausted", failures: 5, retries: 2, attempts: 3, wantErr: true},
+		{name: "no retries", failures: 1, retries: 0, attempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&attempts, 1) <= tt.failures {
+					http.Error(w, "failing", http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer srv.Close()
+
+			wh := newWebhook(t, WebhookConfig{URL: srv.URL, Retries: intPtr(tt.retries)})
+			err := wh.send(Notification{Target: "default"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.attempts {
+				t.Errorf("attempts = %d, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestSendRetryableFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int32
+	}{
+		{name: "bad request", status: http.StatusBadRequest, attempts: 1},
+		{name: "unauthorized", status: http.StatusUnauthorized, attempts: 1},
+		{name: "not found", status: http.StatusNotFound, attempts: 1},
+		{name: "too many requests", status: http.StatusTooManyRequests, attempts: 3},
+		{name: "server error", status: http.StatusInternalServerError, attempts: 3},
+		{name: "unavailable", status: http.StatusServiceUnavailable, attempts: 3},
+		// the connection is closed w/o a response
+		{name: "network error", attempts: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				if tt.status == 0 {
+					conn, _, err := w.(http.Hijacker).Hijack()
+					if err != nil {
+						t.Errorf("Hijack() error = %v", err)
+						return
+					}
+					conn.Close()
+					return
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			wh := newWebhook(t, WebhookConfig{URL: srv.URL, Retries: intPtr(2)})
+			if err := wh.send(Notification{}); err == nil {
+				t.Errorf("send() succeeded, want an error")
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.attempts {
+				t.Errorf("attempts = %d, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestSendNonSuccessStatus(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		wh := newWebhook(t, WebhookConfig{URL: srv.URL, Retries: intPtr(0)})
+		err := wh.send(Notification{})
+		if err == nil || !strings.Contains(err.Error(), "unexpected status") {
+			t.Errorf("status %d: send() error = %v, want unexpected status", status, err)
+		}
+		srv.Close()
+	}
+}
+
+func TestSendSignature(t *testing.T) {
+	secret := "s3cr3t"
+	var body []byte
+	var signature, contentType, custom string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		contentType = r.Header.Get("Content-Type")
+		custom = r.Header.Get("X-Custom")
+	}))
+	defer srv.Close()
+
+	wh := newWebhook(t, WebhookConfig{URL: srv.URL, Secret: secret, Headers: map[string]string{"X-Custom": "value"}})
+	if err := wh.send(Notification{Target: "default", TopPriority: 100, TopASGs: []string{"asg-a"}}); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("signature = %q, want %q", signature, want)
+	}
+	if contentType != "application/json" {
+		t.Errorf("content type = %q, want application/json", contentType)
+	}
+	if custom != "value" {
+		t.Errorf("custom header = %q, want value", custom)
+	}
+	notification := Notification{}
+	if err := json.Unmarshal(body, &notification); err != nil {
+		t.Fatalf("body is not a notification: %v", err)
+	}
+	if notification.TopPriority != 100 || len(notification.TopASGs) != 1 || notification.TopASGs[0] != "asg-a" {
+		t.Errorf("unexpected notification %+v", notification)
+	}
+}
+
+func TestSendWithoutSecret(t *testing.T) {
+	signed := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, signed = r.Header[SignatureHeader]
+	}))
+	defer srv.Close()
+
+	wh := newWebhook(t, WebhookConfig{URL: srv.URL})
+	if err := wh.send(Notification{}); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if signed {
+		t.Errorf("the body is signed w/o a secret")
+	}
+}
+
+func TestSendTemplate(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer srv.Close()
+
+	wh := newWebhook(t, WebhookConfig{URL: srv.URL, Template: `{"text": "{{ .Target }} top is {{ json .TopASGs }}"}`})
+	if err := wh.send(Notification{Target: "prod", TopASGs: []string{"asg-a"}}); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if want := `{"text": "prod top is ["asg-a"]"}`; body != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+}
+
+func TestNewNotifierErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  WebhookConfig
+	}{
+		{name: "no url", cfg: WebhookConfig{Name: "a"}},
+		{name: "invalid template", cfg: WebhookConfig{URL: "http://localhost", Template: "{{ .Target "}},
+		{name: "missing secret env", cfg: WebhookConfig{URL: "http://localhost", SecretFromEnv: "PRIORITY_HELPER_TEST_UNSET"}},
+	}
+	for _, tt := range tests {
+		if _, err := NewNotifier([]WebhookConfig{tt.cfg}); err == nil {
+			t.Errorf("%s: NewNotifier() succeeded, want an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package profiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+// how far in the past the last activation of the schedule entries is looked for
+const lookBack = 31 * 24 * time.Hour
+
+// Config has the named profiles, every one with the fields of the scorer configuration to
+// override, and the schedule activating them. At any time the active profile is the one
+// of the schedule entry activated last, or the default one (the target configuration when empty).
+type Config struct {
+	TimeZone string                     `json:"timeZone"`
+	Default  string                     `json:"default"`
+	Profiles map[string]json.RawMessage `json:"profiles"`
+	Schedule []Entry                    `json:"schedule"`
+}
+
+// Entry activates the profile at the times of the cron expression (minute hour dom month dow)
+type Entry struct {
+	Cron    string `json:"cron"`
+	Profile string `json:"profile"`
+
+	schedule cron.Schedule
+}
+
+// Profile is a named overlay of the scorer configuration, the empty one doesn't change it
+type Profile struct {
+	Name    string
+	Overlay []byte
+}
+
+// LoadConfig reads and validates the YAML file with the profiles and the schedule
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, c.validate()
+}
+
+func (c *Config) validate() error {
+	if _, err := c.location(); err != nil {
+		return err
+	}
+	for name, overlay := range c.Profiles {
+		var sc config.ScorerConfiguration
+		if err := json.Unmarshal(overlay, &sc); err != nil {
+			return fmt.Errorf("profile %s: %v", name, err)
+		}
+	}
+	if _, ok := c.Profiles[c.Default]; c.Default != "" && !ok {
+		return fmt.Errorf("unknown default profile %s", c.Default)
+	}
+	if len(c.Schedule) == 0 {
+		return fmt.Errorf("no schedule")
+	}
+	for i := range c.Schedule {
+		e := &c.Schedule[i]
+		if _, ok := c.Profiles[e.Profile]; !ok {
+			return fmt.Errorf("schedule %d: unknown profile %s", i, e.Profile)
+		}
+		schedule, err := cron.ParseStandard(e.Cron)
+		if err != nil {
+			return fmt.Errorf("schedule %d: %v", i, err)
+		}
+		e.schedule = schedule
+	}
+	return nil
+}
+
+// ValidateFor checks that every profile overlaid on the base scorer configuration gives a valid
+// configuration, so that an invalid profile is rejected with its file and not when it is activated.
+func (c *Config) ValidateFor(base config.ScorerConfiguration) error {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cfg := base
+		if err := config.Overlay(&cfg, c.Profiles[name]); err != nil {
+			return fmt.Errorf("profile %s: %v", name, err)
+		}
+		if err := config.Validate(cfg); err != nil {
+			return fmt.Errorf("profile %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) location() (*time.Location, error) {
+	if c.TimeZone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(c.TimeZone)
+}
+
+func (c *Config) profile(name string) Profile {
+	if name == "" {
+		return Profile{}
+	}
+	return Profile{Name: name, Overlay: c.Profiles[name]}
+}
+
+// Active returns the profile active at the given time and the time of the next activation
+func (c *Config) Active(now time.Time) (Profile, time.Time) {
+	loc, _ := c.location()
+	now = now.In(loc)
+	active, lastActivation := c.Default, time.Time{}
+	var next time.Time
+	for _, e := range c.Schedule {
+		// the last activation is the latest one not after now
+		for t := e.schedule.Next(now.Add(-lookBack)); !t.IsZero() && !t.After(now); t = e.schedule.Next(t) {
+			if t.After(lastActivation) {
+				active, lastActivation = e.Profile, t
+			}
+		}
+		if t := e.schedule.Next(now); !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return c.profile(active), next
+}
+
+// Scheduler applies the active profile at start and at every schedule boundary
+type Scheduler struct {
+	config *Config
+	apply  func(Profile)
+}
+
+func NewScheduler(c *Config, apply func(Profile)) *Scheduler {
+	return &Scheduler{config: c, apply: apply}
+}
+
+func (s *Scheduler) Start(stopCh <-chan struct{}) {
+	go func() {
+		for {
+			profile, next := s.config.Active(time.Now())
+			klog.V(2).Infof("Profile %q is active, next schedule boundary at %s", profile.Name, next)
+			s.apply(profile)
+			if next.IsZero() {
+				return
+			}
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-stopCh:
+				timer.Stop()
+				klog.V(1).Infof("Profiles scheduler: stopped")
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
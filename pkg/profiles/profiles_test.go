@@ -0,0 +1,34 @@
+package profiles
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+func TestValidateFor(t *testing.T) {
+	base := config.ScorerConfiguration{BasePriority: 1000, MalusForPrice: 100, HintsConfigMapName: "hints"}
+	tests := []struct {
+		name    string
+		overlay string
+		wantErr string
+	}{
+		{name: "valid", overlay: `{"malusForPrice": 300}`},
+		{name: "negative malus", overlay: `{"malusForPrice": -1}`, wantErr: "profile p"},
+		{name: "unknown component", overlay: `{"disabledComponents": ["nope"]}`, wantErr: "unknown component"},
+		{name: "wrong type", overlay: `{"malusForPrice": "high"}`, wantErr: "profile p"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Profiles: map[string]json.RawMessage{"p": json.RawMessage(tt.overlay)}}
+			err := c.ValidateFor(base)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("ValidateFor() error = %v", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ValidateFor() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package scorer
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/extscorer"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+// BacktestStep is the outcome of the scores computation for a recorded snapshot
+type BacktestStep struct {
+	Time        time.Time        `json:"time"`
+	TopPriority int              `json:"topPriority"`
+	TopASGs     []string         `json:"topASGs"`
+	TopPrice    float64          `json:"topPrice"`
+	Hours       float64          `json:"hours"`
+	Priorities  map[int][]string `json:"priorities"`
+}
+
+// BacktestReport summarizes how the priorities would have evolved, costs are estimated
+// for a single instance of the chosen ASGs (price × hours, the number of nodes is unknown).
+type BacktestReport struct {
+	From          time.Time          `json:"from"`
+	To            time.Time          `json:"to"`
+	Steps         []BacktestStep     `json:"steps"`
+	TopChanges    int                `json:"topChanges"`
+	EstimatedCost float64            `json:"estimatedCost"`
+	CostByASG     map[string]float64 `json:"costByASG"`
+}
+
+// topOf returns the ASGs the cluster-autoscaler priority expander would choose among (randomly):
+// every ASG gets the highest priority whose regular expressions match its name, the hinted
+// priorities included and the vetoed ASGs left out. The prices are the ones of the chosen ASGs.
+func topOf(result *Result) (int, []string, map[string]float64) {
+	type tier struct {
+		priority int
+		res      []*regexp.Regexp
+	}
+	tiers := []tier{}
+	for prio, names := range result.Priorities {
+		t := tier{priority: prio}
+		for _, name := range names {
+			re, err := regexp.Compile(name)
+			if err != nil {
+				re = regexp.MustCompile(regexp.QuoteMeta(name))
+			}
+			t.res = append(t.res, re)
+		}
+		tiers = append(tiers, t)
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].priority > tiers[j].priority })
+
+	top := -1
+	asgs := []string{}
+	prices := make(map[string]float64)
+	for _, t := range tiers {
+		for _, b := range result.Breakdown {
+			if b.Vetoed {
+				continue
+			}
+			for _, re := range t.res {
+				if re.MatchString(b.ASGName) {
+					asgs = append(asgs, b.ASGName)
+					if b.HasPrice {
+						prices[b.ASGName] = b.Price
+					}
+					break
+				}
+			}
+		}
+		if len(asgs) > 0 {
+			top = t.priority
+			break
+		}
+	}
+	sort.Strings(asgs)
+	return top, asgs, prices
+}
+
+// Backtest replays the snapshots (sorted by time) through the scorer using the given configuration and hints,
+// and the external scorer when not nil, every step lasts until the next snapshot, the last one until `to`.
+// The scoring profiles are not replayed, cfg is used for every snapshot.
+func Backtest(snaps []*Snapshot, to time.Time, cfg config.ScorerConfiguration, hintsSpec HintsSpec, ext *extscorer.Client) (*BacktestReport, error) {
+	if len(snaps) == 0 {
+		return nil, fmt.Errorf("no snapshots to backtest")
+	}
+	hints, err := hintsSpec.toHints()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BacktestReport{
+		From:      snaps[0].Time,
+		To:        to,
+		CostByASG: make(map[string]float64),
+	}
+	for i, snap := range snaps {
+		result := computeScores(snap, cfg, hints)
+		if ext != nil && externalScoringEnabled(cfg) {
+			if err := scoreExternally(ext, extscorer.Request{Target: config.DefaultTargetName}, snap, cfg, hints, result); err != nil {
+				return nil, fmt.Errorf("external scorer failed for the snapshot taken at %s: %v", snap.Time, err)
+			}
+		}
+		top, asgs, prices := topOf(result)
+		// every chosen ASG is equally likely, the ones w/o a price are left out of the cost
+		price := 0.0
+		for _, p := range prices {
+			price += p / float64(len(prices))
+		}
+		end := to
+		if i+1 < len(snaps) {
+			end = snaps[i+1].Time
+		}
+		hours := end.Sub(snap.Time).Hours()
+		if hours < 0 {
+			hours = 0
+		}
+		step := BacktestStep{
+			Time:        snap.Time,
+			TopPriority: top,
+			TopASGs:     asgs,
+			TopPrice:    price,
+			Hours:       hours,
+			Priorities:  result.Priorities,
+		}
+		if i > 0 && !reflect.DeepEqual(report.Steps[i-1].TopASGs, step.TopASGs) {
+			report.TopChanges++
+		}
+		report.EstimatedCost += price * hours
+		for asg, p := range prices {
+			report.CostByASG[asg] += p * hours / float64(len(prices))
+		}
+		report.Steps = append(report.Steps, step)
+	}
+	return report, nil
+}
@@ -0,0 +1,82 @@
+package scorer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopOf(t *testing.T) {
+	breakdown := func(name string, priority int, price float64, vetoed bool) ScoreBreakdown {
+		return ScoreBreakdown{ASGName: name, Name: name, Priority: priority, Price: price, HasPrice: price > 0, Vetoed: vetoed}
+	}
+	tests := []struct {
+		name      string
+		breakdown []ScoreBreakdown
+		hinted    map[int][]string
+		top       int
+		asgs      []string
+		prices    map[string]float64
+	}{
+		{
+			name: "highest tier",
+			breakdown: []ScoreBreakdown{
+				breakdown("app-spot-a", 1100, 0.05, false), breakdown("app-spot-b", 1100, 0, false), breakdown("app-a", 500, 0.1, false),
+			},
+			top: 1100, asgs: []string{"app-spot-a", "app-spot-b"}, prices: map[string]float64{"app-spot-a": 0.05},
+		},
+		{
+			name: "vetoed ASG left out",
+			breakdown: []ScoreBreakdown{
+				breakdown("app-spot-a", 1100, 0.05, true), breakdown("app-a", 500, 0.1, false),
+			},
+			top: 500, asgs: []string{"app-a"}, prices: map[string]float64{"app-a": 0.1},
+		},
+		{
+			name: "hinted priority above the scores",
+			breakdown: []ScoreBreakdown{
+				breakdown("app-spot-a", 1100, 0.05, false), breakdown("app-a", 500, 0.1, false),
+			},
+			hinted: map[int][]string{2000: {"^app-a$"}},
+			top:    2000, asgs: []string{"app-a"}, prices: map[string]float64{"app-a": 0.1},
+		},
+		{
+			name: "hinted priority of a vetoed ASG",
+			breakdown: []ScoreBreakdown{
+				breakdown("app-spot-a", 1100, 0.05, false), breakdown("app-a", 500, 0.1, true),
+			},
+			hinted: map[int][]string{2000: {"^app-a$"}},
+			top:    1100, asgs: []string{"app-spot-a"}, prices: map[string]float64{"app-spot-a": 0.05},
+		},
+		{
+			name: "hinted pattern matching several tiers",
+			breakdown: []ScoreBreakdown{
+				breakdown("app-spot-a", 1100, 0.05, false), breakdown("app-spot-b", 900, 0.04, false), breakdown("app-a", 500, 0.1, false),
+			},
+			hinted: map[int][]string{1000: {"-spot-"}},
+			top:    1100, asgs: []string{"app-spot-a"}, prices: map[string]float64{"app-spot-a": 0.05},
+		},
+		{
+			name: "invalid hinted pattern matched literally",
+			breakdown: []ScoreBreakdown{
+				breakdown("app-a", 500, 0.1, false), breakdown("app(", 100, 0.2, false),
+			},
+			hinted: map[int][]string{600: {"app("}},
+			top:    600, asgs: []string{"app("}, prices: map[string]float64{"app(": 0.2},
+		},
+		{
+			name:      "all vetoed",
+			breakdown: []ScoreBreakdown{breakdown("app-a", 500, 0.1, true)},
+			top:       -1, asgs: []string{}, prices: map[string]float64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &Result{Breakdown: tt.breakdown}
+			result.setPriorities(Hints{priorities: tt.hinted})
+			top, asgs, prices := topOf(result)
+			if top != tt.top || !reflect.DeepEqual(asgs, tt.asgs) || !reflect.DeepEqual(prices, tt.prices) {
+				t.Errorf("topOf() = %d, %v, %v, want %d, %v, %v", top, asgs, prices, tt.top, tt.asgs, tt.prices)
+			}
+		})
+	}
+}
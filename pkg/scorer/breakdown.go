@@ -0,0 +1,66 @@
+package scorer
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/klog"
+)
+
+// BreakdownKey is the key of the breakdown ConfigMap holding the JSON encoded list of ScoreBreakdown
+const BreakdownKey = "breakdown"
+
+// SetBreakdownConfigMap enables the publication of the scores breakdown in a ConfigMap
+// in the same namespace of the output one, see also SetOutputConfigMaps.
+func (s *Scorer) SetBreakdownConfigMap(name string) {
+	s.dataMu.Lock()
+	defer s.dataMu.Unlock()
+	s.breakdownConfigMapName = name
+}
+
+// publishBreakdown creates or updates the breakdown ConfigMap when its content changed
+func (s *Scorer) publishBreakdown(breakdownConfigMapName string, result *Result) error {
+	if breakdownConfigMapName == "" {
+		return nil
+	}
+	data, err := json.Marshal(result.Breakdown)
+	if err != nil {
+		return err
+	}
+
+	cm, err := s.cmLister.ConfigMaps(s.namespace).Get(breakdownConfigMapName)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = s.clientset.CoreV1().ConfigMaps(s.namespace).
+			Create(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: s.namespace,
+					Name:      breakdownConfigMapName,
+				},
+				Data: map[string]string{
+					BreakdownKey: string(data),
+				},
+			})
+		return err
+	}
+
+	if cm.Data[BreakdownKey] == string(data) {
+		klog.V(3).Infof("Update of breakdown config map skipped, no changes")
+		return nil
+	}
+	cm = cm.DeepCopy()
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[BreakdownKey] = string(data)
+	if _, err = s.clientset.CoreV1().ConfigMaps(s.namespace).Update(cm); err != nil {
+		return err
+	}
+	klog.V(2).Infof("Updated breakdown config map %s/%s", s.namespace, breakdownConfigMapName)
+	return nil
+}
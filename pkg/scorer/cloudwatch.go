@@ -0,0 +1,101 @@
+package scorer
+
+import (
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/aws"
+)
+
+// CloudWatchReporter periodically publishes the last computed priorities, score components,
+// prices and probabilities to CloudWatch, with target, cluster, ASG, instance type and AZ dimensions.
+type CloudWatchReporter struct {
+	scorer    *Scorer
+	publisher *aws.CloudWatchPublisher
+	interval  time.Duration
+}
+
+var _ Runnable = &CloudWatchReporter{}
+
+func NewCloudWatchReporter(s *Scorer, publisher *aws.CloudWatchPublisher, interval time.Duration) *CloudWatchReporter {
+	return &CloudWatchReporter{scorer: s, publisher: publisher, interval: interval}
+}
+
+// cloudWatchMetricsFor returns the data points of a result, the target and the cluster (the kubeconfig
+// context, left out when empty) are dimensions too, so that several helpers can share the namespace.
+func cloudWatchMetricsFor(target, cluster string, result *Result) []aws.MetricDatum {
+	data := []aws.MetricDatum{}
+	for _, b := range result.Breakdown {
+		market := "ondemand"
+		if b.IsSpot {
+			market = "spot"
+		}
+		dimensionsWith := func(name, value string) map[string]string {
+			dimensions := map[string]string{
+				"Target":           target,
+				"Cluster":          cluster,
+				"ASG":              b.ASGName,
+				"InstanceType":     b.InstanceType,
+				"AvailabilityZone": b.AvailabilityZone,
+			}
+			if name != "" {
+				dimensions[name] = value
+			}
+			return dimensions
+		}
+		data = append(data, aws.MetricDatum{
+			Name: "Priority", Value: float64(b.Priority), Dimensions: dimensionsWith("", ""), Timestamp: result.Time,
+		})
+		for component, points := range b.Components() {
+			data = append(data, aws.MetricDatum{
+				Name:       "ScoreComponent",
+				Value:      float64(points),
+				Dimensions: dimensionsWith("Component", component),
+				Timestamp:  result.Time,
+			})
+		}
+		if b.HasPrice {
+			data = append(data, aws.MetricDatum{
+				Name:       "Price",
+				Value:      b.Price,
+				Dimensions: dimensionsWith("Market", market),
+				Timestamp:  result.Time,
+			})
+		}
+		if b.IsSpot {
+			data = append(data, aws.MetricDatum{
+				Name: "SpotAdvisorProbability", Value: b.Probability, Dimensions: dimensionsWith("", ""), Timestamp: result.Time,
+			})
+		}
+	}
+	return data
+}
+
+func (r *CloudWatchReporter) publish() {
+	result := r.scorer.LastResult()
+	if result == nil {
+		klog.V(2).Infof("CloudWatch reporter: no priorities computed yet")
+		return
+	}
+	if err := r.publisher.Publish(cloudWatchMetricsFor(r.scorer.target, r.scorer.cluster, result)); err != nil {
+		klog.Errorf("CloudWatch reporter: error publishing metrics: %v", err)
+	}
+}
+
+func (r *CloudWatchReporter) Start(stopCh <-chan struct{}) error {
+	ticker := time.NewTicker(r.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				klog.V(1).Infof("CloudWatch reporter: stopped")
+				return
+			case <-ticker.C:
+				r.publish()
+			}
+		}
+	}()
+	return nil
+}
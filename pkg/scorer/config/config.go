@@ -1,9 +1,46 @@
 package config
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/spf13/pflag"
 )
 
+const (
+	// ScoringModelLinear subtracts from the score the price and the interruption probability, each one with its coefficient
+	ScoringModelLinear = "linear"
+	// ScoringModelExpectedCost ranks the ASGs by their expected cost per hour
+	ScoringModelExpectedCost = "expected-cost"
+)
+
+const (
+	// PriceNormalizationNone uses the hourly price of the instances
+	PriceNormalizationNone = "none"
+	// PriceNormalizationVCPU divides the price by the vCPUs of the instance type
+	PriceNormalizationVCPU = "vcpu"
+	// PriceNormalizationMemory divides the price by the GiB of memory of the instance type
+	PriceNormalizationMemory = "memory"
+	// PriceNormalizationWeighted divides the price by the vCPUs and GiB of memory weighted by PriceVCPUWeight and PriceMemoryWeight
+	PriceNormalizationWeighted = "weighted"
+)
+
+const (
+	// PriceModeAbsolute subtracts int(price * MalusForPrice)
+	PriceModeAbsolute = "absolute"
+	// PriceModeRank subtracts up to MalusForPrice by the rank of the price among the ASGs of the same scope
+	PriceModeRank = "rank"
+	// PriceModeMinMax subtracts up to MalusForPrice by the min-max normalized price among the ASGs of the same scope
+	PriceModeMinMax = "minmax"
+
+	// PriceScopeAll compares the price with the one of all the ASGs
+	PriceScopeAll = "all"
+	// PriceScopePool compares the price with the one of the ASGs of the same pool
+	PriceScopePool = "pool"
+	// PriceScopeVCPU compares the price with the one of the ASGs with the same number of vCPUs
+	PriceScopeVCPU = "vcpu"
+)
+
 const (
 	basePriority                   = 1000
 	malusForOnDemand               = 500
@@ -12,21 +49,93 @@ const (
 	malusForNodeDistribution       = 10
 	malusForNodeDistributionAZOnly = 10
 	malusForPrice                  = 100
+	malusForExpectedCost           = 300
+	interruptionCost               = 168.0
+	spotPriceRatioThreshold        = 1.0
+	priceVCPUWeight                = 1.0
+	priceMemoryWeight              = 0.25
 
 	hintsConfigMapName = "cluster-autoscaler-priority-hints"
 )
 
 type ScorerConfiguration struct {
-	BasePriority                   int
-	MalusForOnDemand               int
-	BonusForSpot                   int
-	MalusForProbability            int
-	MalusForNodeDistribution       int
-	MalusForNodeDistributionAZOnly int
-	MalusForPrice                  int
-
-	IgnoreAZs          bool
-	HintsConfigMapName string
+	BasePriority                   int `json:"basePriority"`
+	MalusForOnDemand               int `json:"malusForOnDemand"`
+	BonusForSpot                   int `json:"bonusForSpot"`
+	MalusForProbability            int `json:"malusForProbability"`
+	MalusForNodeDistribution       int `json:"malusForNodeDistribution"`
+	MalusForNodeDistributionAZOnly int `json:"malusForNodeDistributionAZOnly"`
+	MalusForPrice                  int `json:"malusForPrice"`
+	// BonusForSavings is the bonus of a spot ASG whose savings over on-demand are 100%, the actual
+	// bonus is proportional to the savings advertised by the spot advisor
+	BonusForSavings int `json:"bonusForSavings"`
+	// SpotPriceRatioThreshold is the ratio between the spot and the on-demand price above which a spot ASG
+	// doesn't get the spot and savings bonuses but MalusForExpensiveSpot, 0 disables the check
+	SpotPriceRatioThreshold float64 `json:"spotPriceRatioThreshold"`
+	MalusForExpensiveSpot   int     `json:"malusForExpensiveSpot"`
+
+	// PriceNormalization is how the price is compared, absolute or per unit of capacity (see PriceNormalizationNone and others)
+	PriceNormalization string  `json:"priceNormalization"`
+	PriceVCPUWeight    float64 `json:"priceVCPUWeight"`
+	PriceMemoryWeight  float64 `json:"priceMemoryWeight"`
+
+	// PriceMode is how the price malus is computed, absolute or relative to the other ASGs of the PriceScope,
+	// with the relative modes the malus goes from zero for the cheapest ASG to MalusForPrice for the most expensive
+	PriceMode  string `json:"priceMode"`
+	PriceScope string `json:"priceScope"`
+
+	// ScoringModel is ScoringModelLinear (price and probability maluses) or ScoringModelExpectedCost,
+	// that ranks the ASGs by price plus the expected cost of the interruptions, each one costs InterruptionCost
+	// hours of the instance price (the work lost, the draining and the replacement)
+	ScoringModel         string  `json:"scoringModel"`
+	InterruptionCost     float64 `json:"interruptionCost"`
+	MalusForExpectedCost int     `json:"malusForExpectedCost"`
+
+	// CostVsReliability (0-100) and Diversity (0-100) are dials the coefficients are derived from,
+	// the coefficients set together with a dial win over the derived ones
+	CostVsReliability *int `json:"costVsReliability,omitempty"`
+	Diversity         *int `json:"diversity,omitempty"`
+	// DerivedCoefficients are the names of the coefficients derived from the dials
+	DerivedCoefficients []string `json:"-"`
+
+	IgnoreAZs          bool   `json:"ignoreAZs"`
+	HintsConfigMapName string `json:"hintsConfigMapName"`
+
+	// Expression is a score expression evaluated for every ASG, its value is added to the
+	// built-in score or replaces it depending on ExpressionMode
+	Expression     string `json:"expression"`
+	ExpressionMode string `json:"expressionMode"`
+
+	DisabledComponents []string `json:"disabledComponents"`
+
+	Pools []PoolConfiguration `json:"pools"`
+}
+
+// Components are the score components that can be disabled, named as in the scores breakdown
+var Components = []string{
+	"spot_bonus",
+	"savings_bonus",
+	"expensive_spot_malus",
+	"ondemand_malus",
+	"probability_malus",
+	"node_distribution_malus",
+	"price_malus",
+	"expected_cost_malus",
+	"expression",
+	"hints_bonus",
+	"hints_malus",
+	"external_bonus",
+	"external_malus",
+}
+
+// IsEnabled returns false when the score component is disabled
+func (sc ScorerConfiguration) IsEnabled(component string) bool {
+	for _, c := range sc.DisabledComponents {
+		if c == component {
+			return false
+		}
+	}
+	return true
 }
 
 func BindFlags(sc *ScorerConfiguration, fs *pflag.FlagSet) {
@@ -37,6 +146,90 @@ func BindFlags(sc *ScorerConfiguration, fs *pflag.FlagSet) {
 	fs.IntVar(&sc.MalusForNodeDistribution, "malus-for-nodes-distribution", malusForNodeDistribution, "")
 	fs.IntVar(&sc.MalusForNodeDistributionAZOnly, "malus-for-nodes-distribution-az-only", malusForNodeDistributionAZOnly, "")
 	fs.IntVar(&sc.MalusForPrice, "malus-for-price", malusForPrice, "")
+	fs.StringVar(&sc.PriceNormalization, "price-normalization", PriceNormalizationNone, "How the prices are compared: none (hourly price), vcpu (per vCPU), memory (per GiB) or weighted (per vCPU and GiB weighted)")
+	fs.Float64Var(&sc.PriceVCPUWeight, "price-vcpu-weight", priceVCPUWeight, "Weight of a vCPU in the capacity of the weighted price normalization")
+	fs.Float64Var(&sc.PriceMemoryWeight, "price-memory-weight", priceMemoryWeight, "Weight of a GiB of memory in the capacity of the weighted price normalization")
+	fs.StringVar(&sc.PriceMode, "price-mode", PriceModeAbsolute, "How the price malus is computed: absolute (price * malus-for-price), rank or minmax (up to malus-for-price relatively to the other ASGs)")
+	fs.StringVar(&sc.PriceScope, "price-scope", PriceScopeAll, "ASGs whose prices are compared by the relative price modes and the expected-cost model: all, pool or vcpu (same number of vCPUs)")
+	fs.StringVar(&sc.ScoringModel, "scoring-model", ScoringModelLinear, "Scoring model: linear (price and probability maluses) or expected-cost (ASGs ranked by price plus the expected cost of the interruptions)")
+	fs.Float64Var(&sc.InterruptionCost, "interruption-cost", interruptionCost, "Cost of an interruption for the expected-cost scoring model, in hours of the instance price lost per interruption")
+	fs.IntVar(&sc.MalusForExpectedCost, "malus-for-expected-cost", malusForExpectedCost, "Malus of the ASG with the highest expected cost, the other ones get a part of it by rank")
+	fs.Var(dialValue{&sc.CostVsReliability}, "cost-vs-reliability", "Dial from 0 (cost first) to 100 (reliability first) the spot, on-demand, price and probability coefficients are derived from, the coefficients set explicitly win")
+	fs.Var(dialValue{&sc.Diversity}, "diversity", "Dial from 0 to 100 the node distribution coefficients are derived from, the coefficients set explicitly win")
+	fs.IntVar(&sc.BonusForSavings, "bonus-for-savings", 0, "Bonus of the spot ASGs proportional to the savings over on-demand of the spot advisor, the full bonus is for 100% savings")
+	fs.Float64Var(&sc.SpotPriceRatioThreshold, "spot-price-ratio-threshold", spotPriceRatioThreshold, "Ratio between the spot and on-demand prices above which the spot ASGs lose the spot and savings bonuses, 0 to disable it")
+	fs.IntVar(&sc.MalusForExpensiveSpot, "malus-for-expensive-spot", 0, "Malus of the spot ASGs whose spot price is above the ratio of the on-demand price")
 	fs.BoolVar(&sc.IgnoreAZs, "ignore-availability-zones", false, "")
 	fs.StringVar(&sc.HintsConfigMapName, "hints-configmap", hintsConfigMapName, "")
+	fs.Var(&poolsFile{pools: &sc.Pools}, "pools-config", "YAML file with the ASG pools, every one with its own coefficients and node distribution scope")
+	fs.StringVar(&sc.Expression, "score-expression", "", "Score expression evaluated for every ASG (variables: "+strings.Join(ExpressionVariables, ", ")+"; functions: tag, hasTag)")
+	fs.StringVar(&sc.ExpressionMode, "score-expression-mode", ExpressionModeAdd, "How the score expression is used: add (to the built-in score) or replace (the built-in score)")
+	fs.StringSliceVar(&sc.DisabledComponents, "disabled-components", nil, "Score components to ignore, any of "+strings.Join(Components, ", "))
+}
+
+// Validate checks that the base priority is positive, that the bonus and malus
+// coefficients are not negative, that the scoring model and price mode, scope and
+// normalization are known, that the dials are between 0 and 100, that the score expression
+// is valid, that the hints ConfigMap is set, the disabled components exist and that the pools
+// are valid.
+func Validate(sc ScorerConfiguration) error {
+	if sc.BasePriority <= 0 {
+		return fmt.Errorf("basePriority has to be positive")
+	}
+	for name, value := range sc.coefficients() {
+		if *value < 0 {
+			return fmt.Errorf("%s can't be negative", name)
+		}
+	}
+	switch sc.ScoringModel {
+	case "", ScoringModelLinear, ScoringModelExpectedCost:
+	default:
+		return fmt.Errorf("scoringModel has to be %s or %s", ScoringModelLinear, ScoringModelExpectedCost)
+	}
+	if sc.InterruptionCost < 0 {
+		return fmt.Errorf("interruptionCost can't be negative")
+	}
+	if sc.SpotPriceRatioThreshold < 0 {
+		return fmt.Errorf("spotPriceRatioThreshold can't be negative")
+	}
+	switch sc.PriceMode {
+	case "", PriceModeAbsolute, PriceModeRank, PriceModeMinMax:
+	default:
+		return fmt.Errorf("priceMode has to be %s, %s or %s", PriceModeAbsolute, PriceModeRank, PriceModeMinMax)
+	}
+	switch sc.PriceScope {
+	case "", PriceScopeAll, PriceScopePool, PriceScopeVCPU:
+	default:
+		return fmt.Errorf("priceScope has to be %s, %s or %s", PriceScopeAll, PriceScopePool, PriceScopeVCPU)
+	}
+	switch sc.PriceNormalization {
+	case "", PriceNormalizationNone, PriceNormalizationVCPU, PriceNormalizationMemory:
+	case PriceNormalizationWeighted:
+		if sc.PriceVCPUWeight < 0 || sc.PriceMemoryWeight < 0 || sc.PriceVCPUWeight+sc.PriceMemoryWeight == 0 {
+			return fmt.Errorf("priceVCPUWeight and priceMemoryWeight can't be negative and one of them has to be positive")
+		}
+	default:
+		return fmt.Errorf("priceNormalization has to be %s, %s, %s or %s",
+			PriceNormalizationNone, PriceNormalizationVCPU, PriceNormalizationMemory, PriceNormalizationWeighted)
+	}
+	if err := validateDials(sc); err != nil {
+		return err
+	}
+	if err := validateExpression(sc); err != nil {
+		return err
+	}
+	if sc.HintsConfigMapName == "" {
+		return fmt.Errorf("hintsConfigMapName is mandatory")
+	}
+	for _, c := range sc.DisabledComponents {
+		known := false
+		for _, name := range Components {
+			known = known || c == name
+		}
+		if !known {
+			return fmt.Errorf("unknown component %q in disabledComponents", c)
+		}
+	}
+	_, err := sc.ResolvePools()
+	return err
 }
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	costVsReliabilityDial = "costVsReliability"
+	diversityDial         = "diversity"
+)
+
+// coefficientFlags maps the coefficients to their flags
+var coefficientFlags = map[string]string{
+	"malusForOnDemand":               "malus-for-ondemand",
+	"bonusForSpot":                   "bonus-for-spot",
+	"malusForProbability":            "malus-for-probability",
+	"malusForNodeDistribution":       "malus-for-nodes-distribution",
+	"malusForNodeDistributionAZOnly": "malus-for-nodes-distribution-az-only",
+	"malusForPrice":                  "malus-for-price",
+	"malusForExpectedCost":           "malus-for-expected-cost",
+	"bonusForSavings":                "bonus-for-savings",
+	"malusForExpensiveSpot":          "malus-for-expensive-spot",
+}
+
+func (sc *ScorerConfiguration) coefficients() map[string]*int {
+	return map[string]*int{
+		"malusForOnDemand":               &sc.MalusForOnDemand,
+		"bonusForSpot":                   &sc.BonusForSpot,
+		"malusForProbability":            &sc.MalusForProbability,
+		"malusForNodeDistribution":       &sc.MalusForNodeDistribution,
+		"malusForNodeDistributionAZOnly": &sc.MalusForNodeDistributionAZOnly,
+		"malusForPrice":                  &sc.MalusForPrice,
+		"malusForExpectedCost":           &sc.MalusForExpectedCost,
+		"bonusForSavings":                &sc.BonusForSavings,
+		"malusForExpensiveSpot":          &sc.MalusForExpensiveSpot,
+	}
+}
+
+// dialCoefficients returns the coefficients derived from a dial, 0 is cost (or no diversity)
+// first and 100 is reliability (or diversity) first, 50 gives the default coefficients.
+func dialCoefficients(dial string, value int) map[string]int {
+	// scale a default coefficient between 0 and twice its value
+	scale := func(def int, f float64) int { return int(math.Round(2 * float64(def) * f)) }
+	r := float64(value) / 100
+	switch dial {
+	case costVsReliabilityDial:
+		return map[string]int{
+			"bonusForSpot":        scale(bonusForSpot, 1-r),
+			"malusForOnDemand":    scale(malusForOnDemand, 1-r),
+			"malusForPrice":       scale(malusForPrice, 1-r),
+			"malusForProbability": scale(malusForProbability, r),
+		}
+	case diversityDial:
+		return map[string]int{
+			"malusForNodeDistribution":       scale(malusForNodeDistribution, r),
+			"malusForNodeDistributionAZOnly": scale(malusForNodeDistributionAZOnly, r),
+		}
+	}
+	return nil
+}
+
+// applyDials sets the coefficients derived from the given dials, but the explicit ones,
+// and keeps track of the derived coefficients, the explicit ones are not derived anymore.
+func (sc *ScorerConfiguration) applyDials(dials []string, explicit func(coefficient string) bool) {
+	derived := make(map[string]bool)
+	for _, name := range sc.DerivedCoefficients {
+		derived[name] = !explicit(name)
+	}
+	coefficients := sc.coefficients()
+	for _, dial := range dials {
+		value := sc.CostVsReliability
+		if dial == diversityDial {
+			value = sc.Diversity
+		}
+		if value == nil {
+			continue
+		}
+		for name, v := range dialCoefficients(dial, *value) {
+			if !explicit(name) {
+				*coefficients[name] = v
+				derived[name] = true
+			}
+		}
+	}
+	sc.DerivedCoefficients = nil
+	for name, ok := range derived {
+		if ok {
+			sc.DerivedCoefficients = append(sc.DerivedCoefficients, name)
+		}
+	}
+	sort.Strings(sc.DerivedCoefficients)
+}
+
+// Derived returns the coefficients derived from the dials with their values
+func (sc ScorerConfiguration) Derived() map[string]int {
+	if len(sc.DerivedCoefficients) == 0 {
+		return nil
+	}
+	coefficients := sc.coefficients()
+	res := make(map[string]int)
+	for _, name := range sc.DerivedCoefficients {
+		res[name] = *coefficients[name]
+	}
+	return res
+}
+
+// Overlay applies the fields set in the JSON overlay to the configuration, when the overlay
+// sets a dial the coefficients it doesn't set are derived from the dial.
+func Overlay(sc *ScorerConfiguration, overlay []byte) error {
+	// the dials are copied, the JSON decoder would set the values shared with the original configuration
+	for _, dial := range []**int{&sc.CostVsReliability, &sc.Diversity} {
+		if *dial != nil {
+			value := **dial
+			*dial = &value
+		}
+	}
+	if err := json.Unmarshal(overlay, sc); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(overlay, &fields); err != nil {
+		return err
+	}
+	dials := []string{}
+	for _, dial := range []string{costVsReliabilityDial, diversityDial} {
+		if _, ok := fields[dial]; ok {
+			dials = append(dials, dial)
+		}
+	}
+	sc.applyDials(dials, func(coefficient string) bool {
+		_, ok := fields[coefficient]
+		return ok
+	})
+	return nil
+}
+
+// ApplyDialFlags derives the coefficients from the dials set on the command line,
+// the coefficients set on the command line win over the derived ones.
+func ApplyDialFlags(sc *ScorerConfiguration, fs *pflag.FlagSet) {
+	dials := []string{}
+	for dial, flag := range map[string]string{costVsReliabilityDial: "cost-vs-reliability", diversityDial: "diversity"} {
+		if f := fs.Lookup(flag); f != nil && f.Changed {
+			dials = append(dials, dial)
+		}
+	}
+	sort.Strings(dials)
+	sc.applyDials(dials, func(coefficient string) bool {
+		f := fs.Lookup(coefficientFlags[coefficient])
+		return f != nil && f.Changed
+	})
+}
+
+// dialValue is a flag value for an optional dial
+type dialValue struct {
+	dial **int
+}
+
+func (v dialValue) String() string {
+	if v.dial == nil || *v.dial == nil {
+		return ""
+	}
+	return strconv.Itoa(**v.dial)
+}
+
+func (v dialValue) Type() string { return "int" }
+
+func (v dialValue) Set(s string) error {
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v.dial = &value
+	return nil
+}
+
+func validateDials(sc ScorerConfiguration) error {
+	for name, value := range map[string]*int{costVsReliabilityDial: sc.CostVsReliability, diversityDial: sc.Diversity} {
+		if value != nil && (*value < 0 || *value > 100) {
+			return fmt.Errorf("%s has to be between 0 and 100", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDialCoefficients(t *testing.T) {
+	tests := []struct {
+		dial         string
+		value        int
+		coefficients map[string]int
+	}{
+		{
+			dial:  costVsReliabilityDial,
+			value: 0,
+			coefficients: map[string]int{
+				"bonusForSpot": 2 * bonusForSpot, "malusForOnDemand": 2 * malusForOnDemand,
+				"malusForPrice": 2 * malusForPrice, "malusForProbability": 0,
+			},
+		},
+		{
+			dial:  costVsReliabilityDial,
+			value: 50,
+			coefficients: map[string]int{
+				"bonusForSpot": bonusForSpot, "malusForOnDemand": malusForOnDemand,
+				"malusForPrice": malusForPrice, "malusForProbability": malusForProbability,
+			},
+		},
+		{
+			dial:  costVsReliabilityDial,
+			value: 100,
+			coefficients: map[string]int{
+				"bonusForSpot": 0, "malusForOnDemand": 0,
+				"malusForPrice": 0, "malusForProbability": 2 * malusForProbability,
+			},
+		},
+		{
+			dial:         diversityDial,
+			value:        0,
+			coefficients: map[string]int{"malusForNodeDistribution": 0, "malusForNodeDistributionAZOnly": 0},
+		},
+		{
+			dial:  diversityDial,
+			value: 50,
+			coefficients: map[string]int{
+				"malusForNodeDistribution": malusForNodeDistribution, "malusForNodeDistributionAZOnly": malusForNodeDistributionAZOnly,
+			},
+		},
+		{
+			dial:  diversityDial,
+			value: 100,
+			coefficients: map[string]int{
+				"malusForNodeDistribution": 2 * malusForNodeDistribution, "malusForNodeDistributionAZOnly": 2 * malusForNodeDistributionAZOnly,
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := dialCoefficients(tt.dial, tt.value); !reflect.DeepEqual(got, tt.coefficients) {
+			t.Errorf("dialCoefficients(%s, %d) = %v, want %v", tt.dial, tt.value, got, tt.coefficients)
+		}
+	}
+}
+
+func TestApplyDials(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	tests := []struct {
+		name     string
+		sc       ScorerConfiguration
+		dials    []string
+		explicit []string
+		derived  map[string]int
+	}{
+		{
+			name:  "cost first",
+			sc:    ScorerConfiguration{CostVsReliability: intPtr(0)},
+			dials: []string{costVsReliabilityDial},
+			derived: map[string]int{
+				"bonusForSpot": 2 * bonusForSpot, "malusForOnDemand": 2 * malusForOnDemand,
+				"malusForPrice": 2 * malusForPrice, "malusForProbability": 0,
+			},
+		},
+		{
+			name:     "explicit coefficient wins",
+			sc:       ScorerConfiguration{CostVsReliability: intPtr(100), MalusForPrice: 42},
+			dials:    []string{costVsReliabilityDial},
+			explicit: []string{"malusForPrice"},
+			derived: map[string]int{
+				"bonusForSpot": 0, "malusForOnDemand": 0, "malusForProbability": 2 * malusForProbability,
+			},
+		},
+		{
+			name:  "both dials",
+			sc:    ScorerConfiguration{CostVsReliability: intPtr(50), Diversity: intPtr(100)},
+			dials: []string{costVsReliabilityDial, diversityDial},
+			derived: map[string]int{
+				"bonusForSpot": bonusForSpot, "malusForOnDemand": malusForOnDemand,
+				"malusForPrice": malusForPrice, "malusForProbability": malusForProbability,
+				"malusForNodeDistribution": 2 * malusForNodeDistribution, "malusForNodeDistributionAZOnly": 2 * malusForNodeDistributionAZOnly,
+			},
+		},
+		{
+			name:  "unset dial",
+			sc:    ScorerConfiguration{},
+			dials: []string{costVsReliabilityDial},
+		},
+		{
+			name: "previously derived coefficient made explicit",
+			sc: ScorerConfiguration{
+				Diversity: intPtr(0), MalusForNodeDistribution: 7,
+				DerivedCoefficients: []string{"malusForNodeDistribution", "malusForNodeDistributionAZOnly"},
+			},
+			explicit: []string{"malusForNodeDistribution"},
+			derived:  map[string]int{"malusForNodeDistributionAZOnly": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explicit := func(coefficient string) bool {
+				for _, name := range tt.explicit {
+					if name == coefficient {
+						return true
+					}
+				}
+				return false
+			}
+			sc := tt.sc
+			sc.applyDials(tt.dials, explicit)
+			if derived := sc.Derived(); !reflect.DeepEqual(derived, tt.derived) {
+				t.Errorf("Derived() = %v, want %v", derived, tt.derived)
+			}
+			if tt.explicit != nil && tt.explicit[0] == "malusForPrice" && sc.MalusForPrice != 42 {
+				t.Errorf("the explicit malusForPrice changed to %d", sc.MalusForPrice)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/Knetic/govaluate"
+)
+
+const (
+	// ExpressionModeAdd adds the value of the score expression to the built-in score
+	ExpressionModeAdd = "add"
+	// ExpressionModeReplace uses the value of the score expression in place of the built-in score,
+	// the hints are still applied
+	ExpressionModeReplace = "replace"
+)
+
+// ExpressionVariables are the variables available to the score expression, the unknown
+// prices and spot advisor data are -1
+var ExpressionVariables = []string{
+	"asgName",
+	"pool",
+	"instanceType",
+	"availabilityZone",
+	"region",
+	"isSpot",
+	"spotPrice",
+	"onDemandPrice",
+	"price",
+	"probability",
+	"savings",
+	"cores",
+	"memory",
+	"nodes",
+	"nodesOfType",
+	"nodesInAZ",
+	"totalNodes",
+	"basePriority",
+	"score",
+}
+
+// tagsVariable is the hidden variable with the ASG tags, see NewExpression
+const tagsVariable = "__tags"
+
+// maxCachedExpressions bounds the parsed expressions cache, the what-if requests can bring any expression
+const maxCachedExpressions = 64
+
+var (
+	expressionsMu sync.Mutex
+	expressions   = make(map[string]*Expression)
+)
+
+// Expression is a parsed score expression, it is safe for concurrent use
+type Expression struct {
+	expr *govaluate.EvaluableExpression
+}
+
+// tagArgs returns the tags and the key of a call to tag or hasTag, the tags are the first argument
+func tagArgs(args []interface{}) (map[string]string, string, error) {
+	if len(args) != 2 {
+		return nil, "", fmt.Errorf("one tag key expected")
+	}
+	tags, _ := args[0].(map[string]string)
+	key, ok := args[1].(string)
+	if !ok {
+		return nil, "", fmt.Errorf("the tag key has to be a string")
+	}
+	return tags, key, nil
+}
+
+var expressionFunctions = map[string]govaluate.ExpressionFunction{
+	"tag": func(args ...interface{}) (interface{}, error) {
+		tags, key, err := tagArgs(args)
+		return tags[key], err
+	},
+	"hasTag": func(args ...interface{}) (interface{}, error) {
+		tags, key, err := tagArgs(args)
+		_, found := tags[key]
+		return found, err
+	},
+}
+
+// NewExpression parses a score expression, besides the ExpressionVariables it can use
+// tag(key) that is the value of an ASG tag (empty if missing) and hasTag(key).
+// The expressions are parsed once, the parsed ones are cached.
+func NewExpression(expression string) (*Expression, error) {
+	expressionsMu.Lock()
+	defer expressionsMu.Unlock()
+	if e, ok := expressions[expression]; ok {
+		return e, nil
+	}
+	expr, err := govaluate.NewEvaluableExpressionWithFunctions(expression, expressionFunctions)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range expr.Vars() {
+		known := false
+		for _, name := range ExpressionVariables {
+			known = known || v == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown variable %q", v)
+		}
+	}
+	// the tags are passed to tag and hasTag as a hidden first argument: tag(key) is tag(__tags, key)
+	tokens := []govaluate.ExpressionToken{}
+	original := expr.Tokens()
+	for i, token := range original {
+		tokens = append(tokens, token)
+		if token.Kind != govaluate.CLAUSE || i == 0 || original[i-1].Kind != govaluate.FUNCTION {
+			continue
+		}
+		tokens = append(tokens, govaluate.ExpressionToken{Kind: govaluate.VARIABLE, Value: tagsVariable})
+		if i+1 < len(original) && original[i+1].Kind != govaluate.CLAUSE_CLOSE {
+			tokens = append(tokens, govaluate.ExpressionToken{Kind: govaluate.SEPARATOR, Value: ","})
+		}
+	}
+	if expr, err = govaluate.NewEvaluableExpressionFromTokens(tokens); err != nil {
+		return nil, err
+	}
+	if len(expressions) >= maxCachedExpressions {
+		expressions = make(map[string]*Expression)
+	}
+	e := &Expression{expr: expr}
+	expressions[expression] = e
+	return e, nil
+}
+
+// Evaluate returns the value of the expression for the variables and the tags of an ASG,
+// the value has to be a finite number.
+func (e *Expression) Evaluate(variables map[string]interface{}, tags map[string]string) (float64, error) {
+	parameters := make(map[string]interface{}, len(variables)+1)
+	for k, v := range variables {
+		parameters[k] = v
+	}
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	parameters[tagsVariable] = tags
+	value, err := e.expr.Evaluate(parameters)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := value.(float64)
+	if !ok {
+		return 0, fmt.Errorf("the expression has to be a number, got %v", value)
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("the expression is not a finite number: %v", v)
+	}
+	return v, nil
+}
+
+func validateExpression(sc ScorerConfiguration) error {
+	switch sc.ExpressionMode {
+	case "", ExpressionModeAdd, ExpressionModeReplace:
+	default:
+		return fmt.Errorf("expressionMode has to be %s or %s", ExpressionModeAdd, ExpressionModeReplace)
+	}
+	if sc.Expression == "" {
+		return nil
+	}
+	if _, err := NewExpression(sc.Expression); err != nil {
+		return fmt.Errorf("expression: %v", err)
+	}
+	return nil
+}
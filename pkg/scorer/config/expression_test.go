@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantErr    string
+	}{
+		{expression: "score +", wantErr: ""},
+		{expression: "unknownVariable * 2", wantErr: "unknown variable"},
+		{expression: "now()", wantErr: "Undefined function"},
+		{expression: "(score", wantErr: ""},
+	}
+	for _, tt := range tests {
+		_, err := NewExpression(tt.expression)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("NewExpression(%q) error = %v, want %q", tt.expression, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewExpressionCache(t *testing.T) {
+	e1, err := NewExpression("score + 1")
+	if err != nil {
+		t.Fatalf("NewExpression() error = %v", err)
+	}
+	e2, _ := NewExpression("score + 1")
+	if e1 != e2 {
+		t.Errorf("the expression was parsed twice")
+	}
+	for i := 0; i < 2*maxCachedExpressions; i++ {
+		if _, err := NewExpression(fmt.Sprintf("score + %d", i)); err != nil {
+			t.Fatalf("NewExpression() error = %v", err)
+		}
+	}
+	expressionsMu.Lock()
+	cached := len(expressions)
+	expressionsMu.Unlock()
+	if cached > maxCachedExpressions {
+		t.Errorf("%d cached expressions, want at most %d", cached, maxCachedExpressions)
+	}
+}
+
+func TestExpressionEvaluate(t *testing.T) {
+	variables := map[string]interface{}{"score": 100.0, "isSpot": true, "cores": 4.0, "instanceType": "c5.xlarge"}
+	tags := map[string]string{"team": "batch", "empty": ""}
+	tests := []struct {
+		name       string
+		expression string
+		tags       map[string]string
+		value      float64
+		wantErr    string
+	}{
+		{name: "arithmetic", expression: "score * 2 - cores", value: 196},
+		{name: "ternary", expression: "isSpot && cores <= 4 ? 50 : 0", value: 50},
+		{name: "tag", expression: `tag("team") == "batch" ? 10 : 0`, tags: tags, value: 10},
+		{name: "missing tag", expression: `tag("owner") == "" ? 10 : 0`, tags: tags, value: 10},
+		{name: "hasTag", expression: `(hasTag("empty") ? 1 : 0) + (hasTag("owner") ? 2 : 0)`, tags: tags, value: 1},
+		{name: "no tags", expression: `hasTag("team") ? 1 : 0`, value: 0},
+		{name: "tag in arithmetic", expression: `cores * (tag("team") == "batch" ? 2 : 1) + score`, tags: tags, value: 108},
+		{name: "two tags", expression: `tag("team") == "batch" && !hasTag("owner") ? 5 : 0`, tags: tags, value: 5},
+		{name: "tag w/o key", expression: `tag() == "" ? 1 : 0`, wantErr: "one tag key expected"},
+		{name: "tag with a number", expression: `tag(1) == "" ? 1 : 0`, wantErr: "has to be a string"},
+		{name: "not a number", expression: "isSpot", wantErr: "has to be a number"},
+		{name: "infinite", expression: "score / 0", wantErr: "not a finite number"},
+		{name: "negative infinite", expression: "-score / 0", wantErr: "not a finite number"},
+		{name: "not a number value", expression: "(score - score) / 0", wantErr: "not a finite number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewExpression(tt.expression)
+			if err != nil {
+				t.Fatalf("NewExpression() error = %v", err)
+			}
+			value, err := e.Evaluate(variables, tt.tags)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Evaluate() = %v, %v, want error %q", value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || value != tt.value {
+				t.Errorf("Evaluate() = %v, %v, want %v", value, err, tt.value)
+			}
+		})
+	}
+}
+
+func TestValidateExpression(t *testing.T) {
+	tests := []struct {
+		sc      ScorerConfiguration
+		wantErr bool
+	}{
+		{sc: ScorerConfiguration{}},
+		{sc: ScorerConfiguration{Expression: "score", ExpressionMode: ExpressionModeAdd}},
+		{sc: ScorerConfiguration{Expression: "score", ExpressionMode: ExpressionModeReplace}},
+		{sc: ScorerConfiguration{Expression: "score", ExpressionMode: "multiply"}, wantErr: true},
+		{sc: ScorerConfiguration{Expression: "score +"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validateExpression(tt.sc); (err != nil) != tt.wantErr {
+			t.Errorf("validateExpression(%q, %q) error = %v, wantErr %v", tt.sc.Expression, tt.sc.ExpressionMode, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// NodeTemplateLabelTagPrefix is the prefix of the ASG tags used by the cluster-autoscaler
+// to know the labels of the nodes of an ASG scaled from zero
+const NodeTemplateLabelTagPrefix = "k8s.io/cluster-autoscaler/node-template/label/"
+
+// PoolConfiguration classifies ASGs in a pool with its own coefficients, an ASG is in the first pool
+// whose criteria are all matched: tags (empty value matches any value), node template labels
+// (the CAS node-template label tags) and name pattern.
+type PoolConfiguration struct {
+	Name               string            `json:"name"`
+	Tags               map[string]string `json:"tags"`
+	NodeTemplateLabels map[string]string `json:"nodeTemplateLabels"`
+	NamePattern        string            `json:"namePattern"`
+	// NodeSelector is the label selector of the nodes counted for the node distribution malus, all when empty
+	NodeSelector string `json:"nodeSelector"`
+	// Scorer has the fields of the scorer configuration to override for the ASGs of the pool
+	Scorer json.RawMessage `json:"scorer"`
+}
+
+// Pool is a validated PoolConfiguration, ready to classify ASGs
+type Pool struct {
+	Name         string
+	Config       ScorerConfiguration
+	NodeSelector labels.Selector
+
+	tags        map[string]string
+	namePattern *regexp.Regexp
+}
+
+// LoadPools reads a YAML file with a list of pools
+func LoadPools(path string) ([]PoolConfiguration, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pools []PoolConfiguration
+	if err := yaml.Unmarshal(data, &pools); err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// poolsFile is a flag value loading the pools from a YAML file
+type poolsFile struct {
+	path  string
+	pools *[]PoolConfiguration
+}
+
+func (f *poolsFile) String() string { return f.path }
+func (f *poolsFile) Type() string   { return "string" }
+
+func (f *poolsFile) Set(path string) error {
+	pools, err := LoadPools(path)
+	if err != nil {
+		return err
+	}
+	f.path, *f.pools = path, pools
+	return nil
+}
+
+// ResolvePools returns the pools of the configuration with their own effective configuration
+func (sc ScorerConfiguration) ResolvePools() ([]Pool, error) {
+	base := sc
+	base.Pools = nil
+	res := []Pool{}
+	names := make(map[string]bool)
+	for i, pc := range sc.Pools {
+		if !targetNameRe.MatchString(pc.Name) {
+			return nil, fmt.Errorf("pool %d: invalid name %q, it has to be lowercase alphanumeric or '-'", i, pc.Name)
+		}
+		if names[pc.Name] {
+			return nil, fmt.Errorf("pool %s: duplicated name", pc.Name)
+		}
+		names[pc.Name] = true
+
+		pool := Pool{Name: pc.Name, Config: base, NodeSelector: labels.Everything(), tags: make(map[string]string)}
+		for k, v := range pc.Tags {
+			pool.tags[k] = v
+		}
+		for k, v := range pc.NodeTemplateLabels {
+			pool.tags[NodeTemplateLabelTagPrefix+k] = v
+		}
+		if pc.NamePattern != "" {
+			re, err := regexp.Compile(pc.NamePattern)
+			if err != nil {
+				return nil, fmt.Errorf("pool %s: %v", pc.Name, err)
+			}
+			pool.namePattern = re
+		}
+		if len(pool.tags) == 0 && pool.namePattern == nil {
+			return nil, fmt.Errorf("pool %s: tags, nodeTemplateLabels or namePattern are needed", pc.Name)
+		}
+		if pc.NodeSelector != "" {
+			selector, err := labels.Parse(pc.NodeSelector)
+			if err != nil {
+				return nil, fmt.Errorf("pool %s: %v", pc.Name, err)
+			}
+			pool.NodeSelector = selector
+		}
+		if len(pc.Scorer) > 0 {
+			if err := Overlay(&pool.Config, pc.Scorer); err != nil {
+				return nil, fmt.Errorf("pool %s: %v", pc.Name, err)
+			}
+			if len(pool.Config.Pools) > 0 {
+				return nil, fmt.Errorf("pool %s: pools can't be nested", pc.Name)
+			}
+			if err := Validate(pool.Config); err != nil {
+				return nil, fmt.Errorf("pool %s: %v", pc.Name, err)
+			}
+		}
+		res = append(res, pool)
+	}
+	return res, nil
+}
+
+// Matches tells whether the ASG is in the pool
+func (p Pool) Matches(asgName string, tags map[string]string) bool {
+	for k, v := range p.tags {
+		value, found := tags[k]
+		if !found || (v != "" && v != value) {
+			return false
+		}
+	}
+	return p.namePattern == nil || p.namePattern.MatchString(asgName)
+}
+
+// PoolFor returns the first pool of the ASG, nil when the ASG is not in any pool
+func PoolFor(pools []Pool, asgName string, tags map[string]string) *Pool {
+	for i := range pools {
+		if pools[i].Matches(asgName, tags) {
+			return &pools[i]
+		}
+	}
+	return nil
+}
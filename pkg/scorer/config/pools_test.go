@@ -0,0 +1,121 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestResolvePoolsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pool    PoolConfiguration
+		wantErr string
+	}{
+		{name: "invalid name", pool: PoolConfiguration{Name: "Batch", NamePattern: "^batch-"}, wantErr: "invalid name"},
+		{name: "no criteria", pool: PoolConfiguration{Name: "batch"}, wantErr: "are needed"},
+		{name: "invalid name pattern", pool: PoolConfiguration{Name: "batch", NamePattern: "(batch"}, wantErr: "missing closing )"},
+		{name: "invalid node selector", pool: PoolConfiguration{Name: "batch", NamePattern: "^batch-", NodeSelector: "workload in"}, wantErr: "pool batch"},
+		{
+			name:    "invalid scorer field",
+			pool:    PoolConfiguration{Name: "batch", NamePattern: "^batch-", Scorer: json.RawMessage(`{"malusForPrice": "high"}`)},
+			wantErr: "malusForPrice",
+		},
+		{
+			name:    "nested pools",
+			pool:    PoolConfiguration{Name: "batch", NamePattern: "^batch-", Scorer: json.RawMessage(`{"pools": [{"name": "web"}]}`)},
+			wantErr: "can't be nested",
+		},
+		{
+			name:    "invalid scorer",
+			pool:    PoolConfiguration{Name: "batch", NamePattern: "^batch-", Scorer: json.RawMessage(`{"scoringModel": "random"}`)},
+			wantErr: "pool batch",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := ScorerConfiguration{BasePriority: 1000, HintsConfigMapName: "hints", Pools: []PoolConfiguration{tt.pool}}
+			if _, err := sc.ResolvePools(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ResolvePools() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	sc := ScorerConfiguration{Pools: []PoolConfiguration{
+		{Name: "batch", NamePattern: "^batch-"}, {Name: "batch", NamePattern: "^web-"},
+	}}
+	if _, err := sc.ResolvePools(); err == nil || !strings.Contains(err.Error(), "duplicated name") {
+		t.Errorf("ResolvePools() error = %v, want duplicated name", err)
+	}
+}
+
+func TestResolvePoolsConfig(t *testing.T) {
+	sc := ScorerConfiguration{
+		BasePriority: 1000, MalusForPrice: 100, MalusForProbability: 200, HintsConfigMapName: "hints",
+		Pools: []PoolConfiguration{
+			{Name: "batch", NamePattern: "^batch-", NodeSelector: "workload=batch", Scorer: json.RawMessage(`{"malusForPrice": 500}`)},
+			{Name: "web", NamePattern: "^web-"},
+		},
+	}
+	pools, err := sc.ResolvePools()
+	if err != nil {
+		t.Fatalf("ResolvePools() error = %v", err)
+	}
+	if len(pools) != 2 || pools[0].Name != "batch" || pools[1].Name != "web" {
+		t.Fatalf("ResolvePools() = %v, want batch and web", pools)
+	}
+	// the pool overrides only its own fields, the others come from the scorer configuration
+	batch := pools[0].Config
+	if batch.MalusForPrice != 500 || batch.MalusForProbability != 200 || batch.BasePriority != 1000 || len(batch.Pools) != 0 {
+		t.Errorf("batch config = %+v, want malusForPrice 500 over the scorer configuration", batch)
+	}
+	if pools[0].NodeSelector.String() != "workload=batch" || !pools[1].NodeSelector.Empty() {
+		t.Errorf("node selectors = %q, %q, want workload=batch and everything", pools[0].NodeSelector, pools[1].NodeSelector)
+	}
+	if web := pools[1].Config; web.MalusForPrice != 100 {
+		t.Errorf("web malusForPrice = %d, want the scorer configuration one (100)", web.MalusForPrice)
+	}
+}
+
+func TestPoolFor(t *testing.T) {
+	sc := ScorerConfiguration{Pools: []PoolConfiguration{
+		{Name: "gpu", Tags: map[string]string{"gpu": ""}},
+		{Name: "batch", Tags: map[string]string{"team": "batch"}, NamePattern: "-spot-"},
+		{Name: "labelled", NodeTemplateLabels: map[string]string{"workload": "batch"}},
+		{Name: "spot", NamePattern: "-spot-"},
+	}}
+	pools, err := sc.ResolvePools()
+	if err != nil {
+		t.Fatalf("ResolvePools() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		asgName string
+		tags    map[string]string
+		pool    string
+	}{
+		{name: "tag with any value", asgName: "app-spot-a", tags: map[string]string{"gpu": "nvidia", "team": "batch"}, pool: "gpu"},
+		{name: "tag and name pattern", asgName: "app-spot-a", tags: map[string]string{"team": "batch"}, pool: "batch"},
+		{name: "tag w/o name pattern", asgName: "app-a", tags: map[string]string{"team": "batch"}},
+		{name: "tag with another value", asgName: "app-spot-a", tags: map[string]string{"team": "web"}, pool: "spot"},
+		{
+			name: "node template label", asgName: "app-a",
+			tags: map[string]string{NodeTemplateLabelTagPrefix + "workload": "batch"}, pool: "labelled",
+		},
+		{name: "node template label as plain tag", asgName: "app-a", tags: map[string]string{"workload": "batch"}},
+		{name: "name pattern", asgName: "app-spot-a", pool: "spot"},
+		{name: "no pool", asgName: "app-a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := PoolFor(pools, tt.asgName, tt.tags)
+			name := ""
+			if pool != nil {
+				name = pool.Name
+			}
+			if name != tt.pool {
+				t.Errorf("PoolFor(%s, %v) = %q, want %q", tt.asgName, tt.tags, name, tt.pool)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultTargetName is the name of the target configured by the command line flags
+const DefaultTargetName = "default"
+
+var (
+	targetNameRe             = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	invalidTargetNameCharsRe = regexp.MustCompile(`[^-a-z0-9]+`)
+)
+
+// Target is an independent output of the helper, the ASGs selected by its tags are scored
+// with its own configuration and the priorities are published in its own namespace,
+// of the cluster of its kubeconfig context (the default one when empty).
+type Target struct {
+	Name                   string              `json:"name"`
+	Context                string              `json:"context"`
+	Namespace              string              `json:"namespace"`
+	AutoDiscoverASGsByTags string              `json:"autoDiscoverASGsByTags"`
+	OutputConfigMapName    string              `json:"outputConfigMap"`
+	BreakdownConfigMapName string              `json:"breakdownConfigMap"`
+	Scorer                 ScorerConfiguration `json:"scorer"`
+}
+
+// LoadTargets reads a YAML file with a list of targets, the fields that are not set
+// (scorer configuration included) are taken from the defaults.
+func LoadTargets(path string, defaults Target) ([]Target, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rawTargets []json.RawMessage
+	if err := yaml.Unmarshal(data, &rawTargets); err != nil {
+		return nil, err
+	}
+	targets := []Target{}
+	for i, raw := range rawTargets {
+		target := defaults
+		target.Name = ""
+		if err := json.Unmarshal(raw, &target); err != nil {
+			return nil, fmt.Errorf("target %d: %v", i, err)
+		}
+		// the scorer configuration is overlaid again to derive the coefficients from its dials
+		var fields struct {
+			Scorer json.RawMessage `json:"scorer"`
+		}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("target %d: %v", i, err)
+		}
+		if len(fields.Scorer) > 0 {
+			target.Scorer = defaults.Scorer
+			if err := Overlay(&target.Scorer, fields.Scorer); err != nil {
+				return nil, fmt.Errorf("target %d: %v", i, err)
+			}
+		}
+		targets = append(targets, target)
+	}
+	return targets, ValidateTargets(targets)
+}
+
+// ValidateTargets checks that the targets have valid and unique names, a valid scorer
+// configuration and that they don't publish to the same output ConfigMap.
+func ValidateTargets(targets []Target) error {
+	if len(targets) == 0 {
+		return fmt.Errorf("no targets")
+	}
+	names := make(map[string]bool)
+	outputs := make(map[string]string)
+	for i, target := range targets {
+		if !targetNameRe.MatchString(target.Name) {
+			return fmt.Errorf("target %d: invalid name %q, it has to be lowercase alphanumeric or '-'", i, target.Name)
+		}
+		if names[target.Name] {
+			return fmt.Errorf("target %s: duplicated name", target.Name)
+		}
+		names[target.Name] = true
+		if target.Namespace == "" || target.OutputConfigMapName == "" || target.Scorer.HintsConfigMapName == "" {
+			return fmt.Errorf("target %s: namespace, output and hints ConfigMaps are mandatory", target.Name)
+		}
+		if err := Validate(target.Scorer); err != nil {
+			return fmt.Errorf("target %s: %v", target.Name, err)
+		}
+		if target.AutoDiscoverASGsByTags == "" {
+			return fmt.Errorf("target %s: autoDiscoverASGsByTags is mandatory", target.Name)
+		}
+		for _, cm := range []string{target.OutputConfigMapName, target.BreakdownConfigMapName} {
+			if cm == "" {
+				continue
+			}
+			key := target.Context + ":" + target.Namespace + "/" + cm
+			if other, ok := outputs[key]; ok {
+				return fmt.Errorf("target %s: ConfigMap %s/%s is already published by target %s", target.Name, target.Namespace, cm, other)
+			}
+			outputs[key] = target.Name
+		}
+	}
+	return nil
+}
+
+// TargetNameForContext returns a valid target name for a kubeconfig context, for the EKS
+// contexts (ARNs like arn:aws:eks:<region>:<account>:cluster/<name>) it is the cluster name.
+func TargetNameForContext(context string) string {
+	if idx := strings.LastIndex(context, "/"); idx >= 0 && idx < len(context)-1 {
+		context = context[idx+1:]
+	}
+	name := strings.Trim(invalidTargetNameCharsRe.ReplaceAllString(strings.ToLower(context), "-"), "-")
+	if name == "" {
+		return DefaultTargetName
+	}
+	return name
+}
@@ -0,0 +1,93 @@
+package scorer
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/lint"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/metrics"
+)
+
+const reasonASGConventionViolation = "ASGConventionViolation"
+
+// ConventionsChecker periodically validates the discovered ASGs against the conventions
+// the helper relies on, the violations are exposed as metrics and the new ones as events.
+type ConventionsChecker struct {
+	scorer   *Scorer
+	interval time.Duration
+
+	mu         sync.RWMutex
+	violations []lint.Violation
+}
+
+var _ Runnable = &ConventionsChecker{}
+
+func NewConventionsChecker(s *Scorer, interval time.Duration) *ConventionsChecker {
+	return &ConventionsChecker{scorer: s, interval: interval}
+}
+
+// Violations returns the violations found by the last check
+func (c *ConventionsChecker) Violations() []lint.Violation {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.violations
+}
+
+func (c *ConventionsChecker) check() {
+	infos, err := c.scorer.asgDiscoverer.DescribeASGs()
+	if err != nil {
+		klog.Errorf("ASG conventions check: error describing the ASGs: %v", err)
+		return
+	}
+	c.scorer.dataMu.RLock()
+	ignoreAZs := c.scorer.config.IgnoreAZs
+	c.scorer.dataMu.RUnlock()
+	violations := lint.Check(infos, ignoreAZs)
+
+	c.mu.Lock()
+	previous := make(map[lint.Violation]bool)
+	for _, v := range c.violations {
+		previous[v] = true
+	}
+	c.violations = violations
+	c.mu.Unlock()
+
+	counts := make(map[[2]string]int)
+	for _, v := range violations {
+		counts[[2]string{v.ASGName, v.Rule}]++
+		if !previous[v] {
+			c.scorer.emitEvent(corev1.EventTypeWarning, reasonASGConventionViolation, "%s", v.String())
+		}
+	}
+	metrics.SetConventionViolations(c.scorer.target, counts)
+	klog.V(2).Infof("ASG conventions check: %d violations", len(violations))
+}
+
+func (c *ConventionsChecker) Start(stopCh <-chan struct{}) error {
+	ticker := time.NewTicker(c.interval)
+	go func() {
+		defer ticker.Stop()
+		c.check()
+		for {
+			select {
+			case <-stopCh:
+				klog.V(1).Infof("ASG conventions checker: stopped")
+				return
+			case <-ticker.C:
+				c.check()
+			}
+		}
+	}()
+	return nil
+}
+
+// Handler serves the violations found by the last check as JSON
+func (c *ConventionsChecker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, c.Violations())
+	})
+}
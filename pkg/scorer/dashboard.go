@@ -0,0 +1,129 @@
+package scorer
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+)
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"join": strings.Join,
+	"time": func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>cluster-autoscaler-priority-helper</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; margin: 1em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 3px 6px; text-align: right; }
+th { background: #eee; cursor: pointer; user-select: none; }
+td.text { text-align: left; }
+tr:nth-child(even) { background: #f8f8f8; }
+</style>
+</head>
+<body>
+<h2>Priorities</h2>
+{{ if .Result }}
+<p>Computed at {{ time .Result.Time }} - {{ if .Running }}leading{{ else }}standby, data can be outdated{{ end }}</p>
+<p><input id="filter" type="text" placeholder="filter" size="40" onkeyup="filterTable()"></p>
+<table id="asgs">
+<thead><tr>
+<th>ASG</th><th>Instance types</th><th>AZ</th><th>Market</th><th>Price</th><th>Probability</th><th>Nodes</th>
+<th>Base</th><th>Spot bonus</th><th>Savings bonus</th><th>Expensive spot malus</th><th>On-demand malus</th><th>Probability malus</th><th>Distribution malus</th>
+<th>Price malus</th><th>Expected cost malus</th><th>Expression</th><th>Hints bonus</th><th>Hints malus</th><th>External</th><th>Priority</th>
+</tr></thead>
+<tbody>
+{{ range .Result.Breakdown }}<tr>
+<td class="text">{{ .ASGName }}</td>
+<td class="text">{{ join .InstanceTypes ", " }}</td>
+<td class="text">{{ .AvailabilityZone }}</td>
+<td class="text">{{ if .IsSpot }}spot{{ else }}on-demand{{ end }}</td>
+<td>{{ if .HasPrice }}{{ printf "%.4f" .Price }}{{ end }}{{ if .PriceNotNormalized }} <span title="unknown capacity, the hourly price is compared">*</span>{{ end }}</td>
+<td>{{ if .IsSpot }}{{ printf "%.2f" .Probability }}{{ end }}</td>
+<td>{{ .NodeCount }}</td>
+<td>{{ .Base }}</td>
+<td>{{ .SpotBonus }}</td>
+<td>{{ .SavingsBonus }}</td>
+<td>{{ .ExpensiveSpotMalus }}</td>
+<td>{{ .OnDemandMalus }}</td>
+<td>{{ .ProbabilityMalus }}</td>
+<td>{{ .NodeDistributionMalus }}</td>
+<td>{{ .PriceMalus }}</td>
+<td>{{ .ExpectedCostMalus }}</td>
+<td>{{ .Expression }}</td>
+<td>{{ .HintsBonus }}</td>
+<td>{{ .HintsMalus }}</td>
+<td class="text">{{ if .Vetoed }}vetoed{{ else }}{{ .ExternalBonus }}/-{{ .ExternalMalus }}{{ end }}{{ if .ExternalReason }} ({{ .ExternalReason }}){{ end }}</td>
+<td>{{ .Priority }}</td>
+</tr>{{ end }}
+</tbody>
+</table>
+{{ else }}
+<p>No priorities computed yet.</p>
+{{ end }}
+<h2>Priority changes</h2>
+<table>
+<thead><tr><th>Time</th><th>Cause</th><th>Old top</th><th>New top</th><th>Added</th><th>Removed</th></tr></thead>
+<tbody>
+{{ range .Changes }}<tr>
+<td class="text">{{ time .Time }}</td>
+<td class="text">{{ .Cause }}</td>
+<td class="text">{{ .OldTopPriority }}: {{ join .OldTopASGs ", " }}</td>
+<td class="text">{{ .TopPriority }}: {{ join .TopASGs ", " }}</td>
+<td class="text">{{ join .Added ", " }}</td>
+<td class="text">{{ join .Removed ", " }}</td>
+</tr>{{ end }}
+</tbody>
+</table>
+<script>
+function filterTable() {
+  var f = document.getElementById("filter").value.toLowerCase();
+  var rows = document.querySelectorAll("#asgs tbody tr");
+  for (var i = 0; i < rows.length; i++) {
+    rows[i].style.display = rows[i].textContent.toLowerCase().indexOf(f) >= 0 ? "" : "none";
+  }
+}
+document.querySelectorAll("#asgs th").forEach(function(th, col) {
+  th.addEventListener("click", function() {
+    var tbody = document.querySelector("#asgs tbody");
+    var rows = Array.prototype.slice.call(tbody.rows);
+    var asc = th.dataset.asc !== "true";
+    th.dataset.asc = asc;
+    rows.sort(function(a, b) {
+      var x = a.cells[col].textContent.trim(), y = b.cells[col].textContent.trim();
+      var nx = parseFloat(x), ny = parseFloat(y);
+      var cmp = (!isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y);
+      return asc ? cmp : -cmp;
+    });
+    rows.forEach(function(r) { tbody.appendChild(r); });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+// DashboardHandler serves a read-only web page with the scores of all the discovered ASGs
+// and the history of the priority changes.
+func (s *Scorer) DashboardHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := struct {
+			Result  *Result
+			Running bool
+			Changes []PriorityChange
+		}{
+			Result:  s.LastResult(),
+			Running: s.IsRunning(),
+			Changes: s.PriorityChanges(),
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dashboardTemplate.Execute(w, data); err != nil {
+			klog.Errorf("Error rendering dashboard: %v", err)
+		}
+	})
+}
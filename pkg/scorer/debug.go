@@ -0,0 +1,169 @@
+package scorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/fetcher"
+)
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		klog.Errorf("Error encoding JSON response: %v", err)
+	}
+}
+
+func hintsRulesSpec(rules map[int][]*regexp.Regexp) map[int][]string {
+	res := make(map[int][]string)
+	for prio, reList := range rules {
+		for _, re := range reList {
+			res[prio] = append(res[prio], re.String())
+		}
+	}
+	return res
+}
+
+func (h Hints) spec() HintsSpec {
+	return HintsSpec{
+		Bonus:      hintsRulesSpec(h.bonus),
+		Malus:      hintsRulesSpec(h.malus),
+		Priorities: h.priorities,
+	}
+}
+
+// Ready returns an error when the scorer is running (leading) but the informers
+// or the data sources did not sync yet, a standby instance is considered ready.
+func (s *Scorer) Ready() error {
+	if atomic.LoadInt32(&s.running) == 0 {
+		if s.lec.LeaderElect {
+			return nil
+		}
+		return fmt.Errorf("scorer is not running")
+	}
+	if !s.cmInformer.HasSynced() {
+		return fmt.Errorf("config map informer did not sync yet")
+	}
+	if !s.nodesDistribution.HasSynced() {
+		return fmt.Errorf("node informer did not sync yet")
+	}
+	for _, dm := range []*fetcher.DataManager{s.spotAdvisor.DataManager, s.pricer.DataManager, s.asgDiscoverer.DataManager} {
+		if !dm.HasSynced() {
+			return fmt.Errorf("%s did not sync yet", dm.GetName())
+		}
+	}
+	return nil
+}
+
+// IsRunning returns true when the scorer is started, so leading in case of leader election
+func (s *Scorer) IsRunning() bool {
+	return atomic.LoadInt32(&s.running) == 1
+}
+
+// livenessIntervals is how many refresh intervals the update loop can stay w/o activity
+const livenessIntervals = 3
+
+// Alive returns an error when the scorer is running (leading) but its update loop did not
+// go around, nor updated the output ConfigMap, for a few refresh intervals: it is stuck.
+func (s *Scorer) Alive() error {
+	if atomic.LoadInt32(&s.running) == 0 {
+		return nil
+	}
+	s.dataMu.RLock()
+	last, interval := s.lastHeartbeat, s.refreshInterval
+	if s.lastSuccessfulUpdate.After(last) && !last.IsZero() {
+		last = s.lastSuccessfulUpdate
+	}
+	s.dataMu.RUnlock()
+	if last.IsZero() {
+		// the informers are syncing, the loop is not started yet
+		return nil
+	}
+	if since := time.Since(last); since > livenessIntervals*interval {
+		return fmt.Errorf("update loop stuck, no activity for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+func (s *Scorer) LivenessHandler() http.Handler {
+	return LivenessHandlerFor([]*Scorer{s})
+}
+
+func (s *Scorer) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+}
+
+// DebugHandlers returns the read-only JSON endpoints exposing the data used to compute the priorities
+func (s *Scorer) DebugHandlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		"/debug/asgs": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, s.asgDiscoverer.GetAllDetails())
+		}),
+		"/debug/prices": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			spot, ondemand := s.pricer.GetPrices()
+			writeJSON(w, map[string]map[string]float64{"spot": spot, "ondemand": ondemand})
+		}),
+		"/debug/nodes": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, s.nodesDistribution.GetData())
+		}),
+		"/debug/hints": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.dataMu.RLock()
+			hints := s.hints
+			s.dataMu.RUnlock()
+			writeJSON(w, hints.spec())
+		}),
+		"/debug/priorities": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result := s.LastResult()
+			if result == nil {
+				http.Error(w, ErrNoData.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			writeJSON(w, result)
+		}),
+		"/debug/history": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, s.PriorityChanges())
+		}),
+	}
+}
+
+// LivenessHandlerFor serves the liveness of a set of scorers, one for every target,
+// it is alive when all of them are alive.
+func LivenessHandlerFor(scorers []*Scorer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, s := range scorers {
+			if err := s.Alive(); err != nil {
+				http.Error(w, fmt.Sprintf("%s: %v", s.target, err), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Write([]byte("ok"))
+	})
+}
+
+// ReadinessHandlerFor serves the readiness of a set of scorers, one for every target,
+// it is ready when all of them are ready.
+func ReadinessHandlerFor(scorers []*Scorer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, s := range scorers {
+			if err := s.Ready(); err != nil {
+				http.Error(w, fmt.Sprintf("%s: %v", s.target, err), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Write([]byte("ok"))
+	})
+}
@@ -0,0 +1,298 @@
+package scorer
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/notifier"
+)
+
+// SetNotifier enables the webhook notifications about the priorities changes, it has to be called before Run.
+func (s *Scorer) SetNotifier(n *notifier.Notifier) {
+	s.notifier = n
+}
+
+const (
+	eventSourceComponent = "cluster-autoscaler-priority-helper"
+
+	// a data source failing this number of consecutive times is reported
+	sourceFailuresThreshold = 3
+
+	reasonTopPriorityChanged   = "TopPriorityChanged"
+	reasonASGAdded             = "ASGAdded"
+	reasonASGRemoved           = "ASGRemoved"
+	reasonInvalidHints         = "InvalidHints"
+	reasonSourceFailing        = "SourceFailing"
+	reasonSourceRecovered      = "SourceRecovered"
+	reasonProfileChanged       = "ProfileChanged"
+	reasonExternalScorerFailed = "ExternalScorerFailed"
+	reasonExpensiveSpot        = "ExpensiveSpotPrice"
+	reasonExpensiveSpotEnded   = "SpotPriceRecovered"
+)
+
+// NewEventRecorder returns a recorder publishing the events to the API server
+func NewEventRecorder(cs clientset.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(klog.V(4).Infof)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: cs.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventSourceComponent})
+}
+
+// SetEventRecorder enables the Kubernetes events, emitted against the output ConfigMap and, when
+// the POD_NAME and POD_NAMESPACE environment variables are set, against the helper pod.
+// It has to be called before Run.
+func (s *Scorer) SetEventRecorder(recorder record.EventRecorder) {
+	s.recorder = recorder
+}
+
+func (s *Scorer) handleSourceFailure(name string, failures int, err error) {
+	s.failingSourcesMu.Lock()
+	defer s.failingSourcesMu.Unlock()
+	if failures == sourceFailuresThreshold {
+		s.emitEvent(corev1.EventTypeWarning, reasonSourceFailing, "%s failed %d consecutive times: %v", name, failures, err)
+	} else if failures == 0 && s.failingSources[name] {
+		s.emitEvent(corev1.EventTypeNormal, reasonSourceRecovered, "%s recovered", name)
+	}
+	s.failingSources[name] = failures >= sourceFailuresThreshold
+	s.sourceFailures[name] = failures
+	if err != nil {
+		s.sourceLastErrors[name] = err.Error()
+	} else {
+		delete(s.sourceLastErrors, name)
+	}
+}
+
+// degradedSources returns the data sources that are failing repeatedly
+func (s *Scorer) degradedSources() []string {
+	s.failingSourcesMu.Lock()
+	defer s.failingSourcesMu.Unlock()
+	res := []string{}
+	for name, failing := range s.failingSources {
+		if failing {
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (s *Scorer) eventTargets() []*corev1.ObjectReference {
+	outConfigMapName, _ := s.OutputConfigMaps()
+	refs := []*corev1.ObjectReference{{
+		Kind:       "ConfigMap",
+		APIVersion: "v1",
+		Namespace:  s.namespace,
+		Name:       outConfigMapName,
+	}}
+	podName, podNamespace := os.Getenv("POD_NAME"), os.Getenv("POD_NAMESPACE")
+	if podName != "" && podNamespace != "" {
+		refs = append(refs, &corev1.ObjectReference{
+			Kind:       "Pod",
+			APIVersion: "v1",
+			Namespace:  podNamespace,
+			Name:       podName,
+		})
+	}
+	return refs
+}
+
+func (s *Scorer) emitEvent(eventtype, reason, messageFmt string, args ...interface{}) {
+	if s.recorder == nil {
+		return
+	}
+	for _, ref := range s.eventTargets() {
+		s.recorder.Eventf(ref, eventtype, reason, messageFmt, args...)
+	}
+}
+
+// reportExpensiveSpots emits an event when the spot price of an ASG goes above the threshold
+// ratio of the on-demand price and when it goes back below it.
+func (s *Scorer) reportExpensiveSpots(result *Result) {
+	expensive := make(map[string]bool)
+	for _, b := range result.Breakdown {
+		if b.ExpensiveSpot {
+			expensive[b.ASGName] = true
+		}
+	}
+	// the scores are computed by the loop and by the informer handlers, the events are emitted w/o the lock
+	s.dataMu.Lock()
+	previous := s.expensiveSpots
+	s.expensiveSpots = expensive
+	s.dataMu.Unlock()
+	for _, b := range result.Breakdown {
+		if b.ExpensiveSpot && !previous[b.ASGName] {
+			s.emitEvent(corev1.EventTypeWarning, reasonExpensiveSpot,
+				"Spot price of ASG %s (%s in %s) is %.2f times the on-demand price, spot bonuses removed",
+				b.ASGName, b.InstanceType, b.AvailabilityZone, b.SpotPriceRatio)
+		}
+	}
+	for _, b := range result.Breakdown {
+		if previous[b.ASGName] && !expensive[b.ASGName] {
+			s.emitEvent(corev1.EventTypeNormal, reasonExpensiveSpotEnded,
+				"Spot price of ASG %s is back to %.2f times the on-demand price", b.ASGName, b.SpotPriceRatio)
+		}
+	}
+}
+
+// reportHintsErrors emits an event when hints can't be parsed, only when the errors are changing
+// because the hints are parsed at every update.
+func (s *Scorer) reportHintsErrors(parseErrors []string) {
+	msg := strings.Join(parseErrors, "; ")
+	s.dataMu.Lock()
+	changed := msg != s.lastHintsErrors
+	s.lastHintsErrors = msg
+	s.dataMu.Unlock()
+	if changed && msg != "" {
+		s.emitEvent(corev1.EventTypeWarning, reasonInvalidHints, "Invalid hints in %s/%s: %s",
+			s.namespace, s.hintsConfigMapName(), msg)
+	}
+}
+
+func topASGsOf(priorities map[int][]string) (int, []string) {
+	top := -1
+	for prio := range priorities {
+		if prio > top {
+			top = prio
+		}
+	}
+	asgs := append([]string{}, priorities[top]...)
+	sort.Strings(asgs)
+	return top, asgs
+}
+
+func asgSetOf(priorities map[int][]string) map[string]struct{} {
+	res := make(map[string]struct{})
+	for _, asgs := range priorities {
+		for _, asg := range asgs {
+			res[asg] = struct{}{}
+		}
+	}
+	return res
+}
+
+// maxPriorityChanges is the number of priority changes kept in memory
+const maxPriorityChanges = 100
+
+// PriorityChange describes an update of the published priorities
+type PriorityChange struct {
+	Time           time.Time `json:"time"`
+	Cause          string    `json:"cause"`
+	OldTopPriority int       `json:"oldTopPriority"`
+	OldTopASGs     []string  `json:"oldTopASGs"`
+	TopPriority    int       `json:"topPriority"`
+	TopASGs        []string  `json:"topASGs"`
+	Added          []string  `json:"added,omitempty"`
+	Removed        []string  `json:"removed,omitempty"`
+}
+
+func (s *Scorer) recordPriorityChange(change PriorityChange) {
+	s.dataMu.Lock()
+	defer s.dataMu.Unlock()
+	s.priorityChanges = append(s.priorityChanges, change)
+	if len(s.priorityChanges) > maxPriorityChanges {
+		s.priorityChanges = s.priorityChanges[len(s.priorityChanges)-maxPriorityChanges:]
+	}
+}
+
+// PriorityChanges returns the last priority changes, most recent first
+func (s *Scorer) PriorityChanges() []PriorityChange {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+	res := make([]PriorityChange, 0, len(s.priorityChanges))
+	for i := len(s.priorityChanges) - 1; i >= 0; i-- {
+		res = append(res, s.priorityChanges[i])
+	}
+	return res
+}
+
+// publishedPriorities returns the priorities currently in the output ConfigMap, nil if missing or invalid
+func (s *Scorer) publishedPriorities() map[int][]string {
+	var priorities map[int][]string
+	outConfigMapName, _ := s.OutputConfigMaps()
+	cm, err := s.cmLister.ConfigMaps(s.namespace).Get(outConfigMapName)
+	if err != nil {
+		return nil
+	}
+	if err := yaml.Unmarshal([]byte(cm.Data["priorities"]), &priorities); err != nil {
+		return nil
+	}
+	return priorities
+}
+
+// reportPrioritiesChanges emits the events and the notifications about the differences between
+// the previously published priorities and the new ones.
+func (s *Scorer) reportPrioritiesChanges(cause string, oldPriorities, newPriorities map[int][]string) {
+	if oldPriorities == nil {
+		return
+	}
+	oldTop, oldTopASGs := topASGsOf(oldPriorities)
+	newTop, newTopASGs := topASGsOf(newPriorities)
+	if s.notifier != nil {
+		degraded := s.degradedSources()
+		outConfigMapName, _ := s.OutputConfigMaps()
+		s.notifier.Notify(notifier.Notification{
+			Time:            time.Now(),
+			Target:          s.target,
+			Cluster:         s.cluster,
+			Namespace:       s.namespace,
+			ConfigMap:       outConfigMapName,
+			Cause:           cause,
+			OldPriorities:   oldPriorities,
+			NewPriorities:   newPriorities,
+			OldTopPriority:  oldTop,
+			OldTopASGs:      oldTopASGs,
+			TopPriority:     newTop,
+			TopASGs:         newTopASGs,
+			Degraded:        len(degraded) > 0,
+			DegradedSources: degraded,
+		})
+	}
+	if fmt.Sprint(oldTopASGs) != fmt.Sprint(newTopASGs) {
+		s.emitEvent(corev1.EventTypeNormal, reasonTopPriorityChanged, "Top priority changed from %v (%d) to %v (%d)",
+			oldTopASGs, oldTop, newTopASGs, newTop)
+	}
+
+	oldASGs, newASGs := asgSetOf(oldPriorities), asgSetOf(newPriorities)
+	added, removed := []string{}, []string{}
+	for asg := range newASGs {
+		if _, found := oldASGs[asg]; !found {
+			added = append(added, asg)
+		}
+	}
+	for asg := range oldASGs {
+		if _, found := newASGs[asg]; !found {
+			removed = append(removed, asg)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	s.recordPriorityChange(PriorityChange{
+		Time:           time.Now(),
+		Cause:          cause,
+		OldTopPriority: oldTop,
+		OldTopASGs:     oldTopASGs,
+		TopPriority:    newTop,
+		TopASGs:        newTopASGs,
+		Added:          added,
+		Removed:        removed,
+	})
+	if len(added) > 0 {
+		s.emitEvent(corev1.EventTypeNormal, reasonASGAdded, "ASGs added to the priorities: %s", strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		s.emitEvent(corev1.EventTypeWarning, reasonASGRemoved, "ASGs removed from the priorities: %s", strings.Join(removed, ", "))
+	}
+}
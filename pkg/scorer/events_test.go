@@ -0,0 +1,91 @@
+package scorer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+// newSpotSnapshot returns a snapshot with a spot ASG whose on-demand price is 0.1
+func newSpotSnapshot(spotPrice float64) *Snapshot {
+	snap := NewSnapshot(time.Now())
+	snap.ASGs["app-spot-eu-west-1a"] = ASGSnapshot{InstanceTypes: []string{"m5.large"}, AvailabilityZone: "eu-west-1a", IsSpot: true}
+	snap.SpotPrices[priceKey("m5.large", "eu-west-1a", true)] = spotPrice
+	snap.OnDemandPrices[priceKey("m5.large", "eu-west-1a", false)] = 0.1
+	snap.Advisor[advisorKey("eu-west-1", "m5.large")] = AdvisorData{Probability: 0, Saving: 50}
+	return snap
+}
+
+func TestExpensiveSpot(t *testing.T) {
+	tests := []struct {
+		name      string
+		spotPrice float64
+		threshold float64
+		expensive bool
+	}{
+		{name: "cheap", spotPrice: 0.03, threshold: 1},
+		{name: "at the threshold", spotPrice: 0.1, threshold: 1},
+		{name: "above the threshold", spotPrice: 0.11, threshold: 1, expensive: true},
+		{name: "above a lower threshold", spotPrice: 0.09, threshold: 0.8, expensive: true},
+		{name: "check disabled", spotPrice: 0.2, threshold: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.ScorerConfiguration{
+				BasePriority: 1000, BonusForSpot: 100, BonusForSavings: 200, MalusForExpensiveSpot: 30,
+				SpotPriceRatioThreshold: tt.threshold,
+			}
+			b := computeScores(newSpotSnapshot(tt.spotPrice), cfg, Hints{}).Breakdown[0]
+			if b.ExpensiveSpot != tt.expensive || b.SpotPriceRatio != tt.spotPrice/0.1 {
+				t.Errorf("expensive %v with ratio %.2f, want %v with %.2f", b.ExpensiveSpot, b.SpotPriceRatio, tt.expensive, tt.spotPrice/0.1)
+			}
+			spotBonus, savingsBonus, malus := 100, 100, 0
+			if tt.expensive {
+				spotBonus, savingsBonus, malus = 0, 0, 30
+			}
+			if b.SpotBonus != spotBonus || b.SavingsBonus != savingsBonus || b.ExpensiveSpotMalus != malus {
+				t.Errorf("spot bonus %d, savings bonus %d, expensive spot malus %d, want %d, %d, %d",
+					b.SpotBonus, b.SavingsBonus, b.ExpensiveSpotMalus, spotBonus, savingsBonus, malus)
+			}
+			if b.Priority != 1000+spotBonus+savingsBonus-malus {
+				t.Errorf("priority = %d, want %d", b.Priority, 1000+spotBonus+savingsBonus-malus)
+			}
+		})
+	}
+}
+
+func TestReportExpensiveSpots(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	s := &Scorer{recorder: recorder}
+	cfg := config.ScorerConfiguration{BasePriority: 1000, SpotPriceRatioThreshold: 1}
+	// the spot price goes above the on-demand one, stays there and goes back below it twice
+	steps := []struct {
+		spotPrice float64
+		events    []string
+	}{
+		{spotPrice: 0.05},
+		{spotPrice: 0.12, events: []string{"Warning " + reasonExpensiveSpot}},
+		{spotPrice: 0.15},
+		{spotPrice: 0.06, events: []string{"Normal " + reasonExpensiveSpotEnded}},
+		{spotPrice: 0.05},
+	}
+	for i, step := range steps {
+		s.reportExpensiveSpots(computeScores(newSpotSnapshot(step.spotPrice), cfg, Hints{}))
+		events := []string{}
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		if len(events) != len(step.events) {
+			t.Fatalf("step %d: events %v, want %v", i, events, step.events)
+		}
+		for j, event := range events {
+			if !strings.HasPrefix(event, step.events[j]+" ") || !strings.Contains(event, "app-spot-eu-west-1a") {
+				t.Errorf("step %d: event %q, want %s", i, event, step.events[j])
+			}
+		}
+	}
+}
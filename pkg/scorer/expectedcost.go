@@ -0,0 +1,31 @@
+package scorer
+
+import (
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+const (
+	// hoursPerMonth is the period of the interruption frequency of the spot advisor
+	hoursPerMonth = 30 * 24
+	// worstProbability is the spot advisor range of the most interrupted instance types (>20%)
+	worstProbability = 4
+)
+
+// interruptionRate returns the expected interruptions per hour of a spot instance given the spot advisor
+// range (0 is <5%, 4 is >20%), the middle of the range is taken, the worst one when unknown.
+func interruptionRate(probability float64) float64 {
+	if probability < 0 {
+		probability = worstProbability
+	}
+	return (probability*5 + 2.5) / 100 / hoursPerMonth
+}
+
+// expectedCost returns the expected cost per hour of an ASG instance: its price plus, for spot,
+// the cost of the expected interruptions, each one losing InterruptionCost hours of the instance price.
+func expectedCost(cfg config.ScorerConfiguration, b ScoreBreakdown) float64 {
+	cost := b.Price
+	if b.IsSpot {
+		cost += interruptionRate(b.Probability) * cfg.InterruptionCost * b.Price
+	}
+	return cost
+}
@@ -0,0 +1,47 @@
+package scorer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+func TestExpectedCost(t *testing.T) {
+	snap := NewSnapshot(time.Now())
+	snap.Advisor[advisorKey("eu-west-1", "m5.large")] = AdvisorData{Probability: 0}
+	snap.Advisor[advisorKey("eu-west-1", "c5.large")] = AdvisorData{Probability: 4}
+	snap.Advisor[advisorKey("eu-west-1", "r5.large")] = AdvisorData{Probability: -1}
+
+	// with a month of price per interruption the expected cost is the price plus the interrupted share of it
+	cfg := config.ScorerConfiguration{InterruptionCost: hoursPerMonth}
+	tests := []struct {
+		name          string
+		instanceTypes []string
+		isSpot        bool
+		probability   float64
+		cost          float64
+	}{
+		{name: "on-demand", instanceTypes: []string{"c5.large"}, probability: 4, cost: 1},
+		{name: "known <5%", instanceTypes: []string{"m5.large"}, isSpot: true, probability: 0, cost: 1.025},
+		{name: "known >20%", instanceTypes: []string{"c5.large"}, isSpot: true, probability: 4, cost: 1.225},
+		{name: "unknown", instanceTypes: []string{"x9.large"}, isSpot: true, probability: 4, cost: 1.225},
+		{name: "unknown advisor range", instanceTypes: []string{"r5.large"}, isSpot: true, probability: 4, cost: 1.225},
+		{name: "mixed known", instanceTypes: []string{"m5.large", "c5.large"}, isSpot: true, probability: 2, cost: 1.125},
+		{name: "mixed with unknown", instanceTypes: []string{"c5.large", "x9.large"}, isSpot: true, probability: 4, cost: 1.225},
+		{name: "mixed with unknown advisor range", instanceTypes: []string{"m5.large", "r5.large"}, isSpot: true, probability: 0, cost: 1.025},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probability := snap.GetAverageProbabilityFor("eu-west-1", tt.instanceTypes)
+			if probability != tt.probability {
+				t.Errorf("probability = %.2f, want %.2f", probability, tt.probability)
+			}
+			b := ScoreBreakdown{Price: 1, IsSpot: tt.isSpot, Probability: probability}
+			if cost := expectedCost(cfg, b); math.Abs(cost-tt.cost) > 1e-9 {
+				t.Errorf("expectedCost() = %.4f, want %.4f", cost, tt.cost)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package scorer
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/nodes"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/utils"
+)
+
+// evaluateExpression returns the value of the score expression of the configuration for an ASG,
+// score is the built-in score computed so far.
+func evaluateExpression(snap *Snapshot, cfg config.ScorerConfiguration, breakdown ScoreBreakdown, iDetails utils.InstanceDetails, nodeCounts nodes.Counts, score int) (int, error) {
+	expr, err := config.NewExpression(cfg.Expression)
+	if err != nil {
+		return 0, err
+	}
+	region := iDetails.GetRegion()
+	price := func(isSpot bool) float64 {
+		if p, found := snap.GetPriceFor(iDetails.InstanceType, iDetails.AvailabilityZone, isSpot); found {
+			return p
+		}
+		return -1
+	}
+	probability := float64(snap.GetProbabilityFor(region, iDetails.InstanceType))
+	if iDetails.IsSpot {
+		probability = breakdown.Probability
+	}
+	totalNodes := 0
+	for _, count := range nodeCounts {
+		totalNodes += count
+	}
+	parameters := map[string]interface{}{
+		"asgName":          breakdown.ASGName,
+		"pool":             breakdown.Pool,
+		"instanceType":     iDetails.InstanceType,
+		"availabilityZone": iDetails.AvailabilityZone,
+		"region":           region,
+		"isSpot":           iDetails.IsSpot,
+		"spotPrice":        price(true),
+		"onDemandPrice":    price(false),
+		"price":            price(iDetails.IsSpot),
+		"probability":      probability,
+		"savings":          float64(snap.GetSavingFor(region, iDetails.InstanceType)),
+		"cores":            float64(snap.GetCoresFor(region, iDetails.InstanceType)),
+		"memory":           snap.GetMemoryFor(region, iDetails.InstanceType),
+		"nodes":            float64(breakdown.NodeCount),
+		"nodesOfType":      float64(nodeCounts.GetCountForInstanceType(iDetails.InstanceType)),
+		"nodesInAZ":        float64(nodeCounts.GetCountForAZ(iDetails.AvailabilityZone)),
+		"totalNodes":       float64(totalNodes),
+		"basePriority":     float64(cfg.BasePriority),
+		"score":            float64(score),
+	}
+	value, err := expr.Evaluate(parameters, snap.ASGs[breakdown.ASGName].Tags)
+	if err != nil {
+		return 0, err
+	}
+	// the priorities are int32 in the priority expander
+	if math.Abs(value) > math.MaxInt32 {
+		return 0, fmt.Errorf("the expression value %v is out of range", value)
+	}
+	return int(math.Round(value)), nil
+}
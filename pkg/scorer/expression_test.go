@@ -0,0 +1,51 @@
+package scorer
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+func TestScoreExpression(t *testing.T) {
+	snap := NewSnapshot(time.Now())
+	snap.ASGs["app-eu-west-1a"] = ASGSnapshot{
+		InstanceTypes: []string{"m5.large"}, AvailabilityZone: "eu-west-1a", Tags: map[string]string{"team": "batch"},
+	}
+	hints := Hints{bonus: map[int][]*regexp.Regexp{10: {regexp.MustCompile("^app-")}}}
+	// the built-in score of the on-demand ASG is 1000 - 500
+	tests := []struct {
+		name       string
+		expression string
+		mode       string
+		disabled   []string
+		value      int
+		onDemand   int
+		priority   int
+	}{
+		{name: "add", expression: "50", mode: config.ExpressionModeAdd, value: 50, onDemand: 500, priority: 560},
+		{name: "default mode adds", expression: "-score / 5", value: -100, onDemand: 500, priority: 410},
+		{name: "replace", expression: "score / 2", mode: config.ExpressionModeReplace, value: 250, priority: 260},
+		{name: "tag", expression: `tag("team") == "batch" ? 100 : 0`, value: 100, onDemand: 500, priority: 610},
+		{name: "non-finite ignored", expression: "score / 0", mode: config.ExpressionModeReplace, onDemand: 500, priority: 510},
+		{name: "disabled", expression: "50", disabled: []string{"expression"}, onDemand: 500, priority: 510},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.ScorerConfiguration{
+				BasePriority: 1000, MalusForOnDemand: 500,
+				Expression: tt.expression, ExpressionMode: tt.mode, DisabledComponents: tt.disabled,
+			}
+			result := computeScores(snap, cfg, hints)
+			if len(result.Breakdown) != 1 {
+				t.Fatalf("breakdown = %v", result.Breakdown)
+			}
+			b := result.Breakdown[0]
+			if b.Expression != tt.value || b.OnDemandMalus != tt.onDemand || b.HintsBonus != 10 || b.Priority != tt.priority {
+				t.Errorf("expression %d, on-demand malus %d, hints bonus %d, priority %d, want %d, %d, 10, %d",
+					b.Expression, b.OnDemandMalus, b.HintsBonus, b.Priority, tt.value, tt.onDemand, tt.priority)
+			}
+		})
+	}
+}
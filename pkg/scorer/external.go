@@ -0,0 +1,93 @@
+package scorer
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/extscorer"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/metrics"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+// SetExternalScorer enables the external scorer, it has to be called before Run.
+func (s *Scorer) SetExternalScorer(c *extscorer.Client) {
+	s.externalScorer = c
+}
+
+// applyExternalScorer sends the scored ASGs to the external scorer and applies its decisions to the result,
+// when it fails the result is left untouched (fail open) or an error is returned (fail closed).
+func (s *Scorer) applyExternalScorer(snap *Snapshot, cfg config.ScorerConfiguration, hints Hints, result *Result) error {
+	if s.externalScorer == nil || !externalScoringEnabled(cfg) {
+		return nil
+	}
+	err := scoreExternally(s.externalScorer, extscorer.Request{Target: s.target, Cluster: s.cluster}, snap, cfg, hints, result)
+	if err == nil {
+		metrics.IncExternalScorerRequests(s.target, "ok")
+		return nil
+	}
+	metrics.IncExternalScorerRequests(s.target, "error")
+	s.emitEvent(corev1.EventTypeWarning, reasonExternalScorerFailed, "External scorer failed: %v", err)
+	if s.externalScorer.FailClosed() {
+		return fmt.Errorf("external scorer failed, priorities not updated: %v", err)
+	}
+	klog.Errorf("External scorer failed, using the built-in scores: %v", err)
+	return nil
+}
+
+func externalScoringEnabled(cfg config.ScorerConfiguration) bool {
+	return cfg.IsEnabled("external_bonus") || cfg.IsEnabled("external_malus")
+}
+
+// scoreExternally completes the request with the scored ASGs, sends it to the external scorer
+// and applies its decisions to the result, it is shared with the backtest.
+func scoreExternally(client *extscorer.Client, request extscorer.Request, snap *Snapshot, cfg config.ScorerConfiguration, hints Hints, result *Result) error {
+	request.Time = result.Time
+	for _, b := range result.Breakdown {
+		request.ASGs = append(request.ASGs, extscorer.ASG{
+			Name:             b.ASGName,
+			Pool:             b.Pool,
+			InstanceType:     b.InstanceType,
+			InstanceTypes:    b.InstanceTypes,
+			AvailabilityZone: b.AvailabilityZone,
+			IsSpot:           b.IsSpot,
+			Price:            b.Price,
+			HasPrice:         b.HasPrice,
+			Probability:      b.Probability,
+			NodeCount:        b.NodeCount,
+			Tags:             snap.ASGs[b.ASGName].Tags,
+			Components:       b.Components(),
+			Priority:         b.Priority,
+		})
+	}
+	decisions, err := client.Score(request)
+	if err != nil {
+		return err
+	}
+	applyDecisions(result, cfg, hints, decisions)
+	return nil
+}
+
+// applyDecisions adds the external bonus and malus to the priorities of the ASGs and leaves out the vetoed ones
+func applyDecisions(result *Result, cfg config.ScorerConfiguration, hints Hints, decisions map[string]extscorer.Decision) {
+	for i := range result.Breakdown {
+		b := &result.Breakdown[i]
+		d, ok := decisions[b.ASGName]
+		if !ok {
+			continue
+		}
+		if cfg.IsEnabled("external_bonus") {
+			b.ExternalBonus = d.Bonus
+		}
+		if cfg.IsEnabled("external_malus") {
+			b.ExternalMalus = d.Malus
+		}
+		b.Priority += b.ExternalBonus - b.ExternalMalus
+		if b.Priority < 0 {
+			b.Priority = 0
+		}
+		b.Vetoed, b.ExternalReason = d.Veto, d.Reason
+	}
+	result.setPriorities(hints)
+}
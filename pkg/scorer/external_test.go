@@ -0,0 +1,154 @@
+package scorer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/extscorer"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+// newExternalResult returns a snapshot and a result of three ASGs with the priorities 50, 40 and 30
+func newExternalResult() (*Snapshot, *Result) {
+	snap := NewSnapshot(time.Now())
+	result := &Result{Time: snap.Time}
+	for i, name := range []string{"asg-a", "asg-b", "asg-c"} {
+		snap.ASGs[name] = ASGSnapshot{}
+		result.Breakdown = append(result.Breakdown, ScoreBreakdown{ASGName: name, Name: name, Base: 50 - 10*i, Priority: 50 - 10*i})
+	}
+	result.setPriorities(Hints{})
+	return snap, result
+}
+
+func newExternalScorer(t *testing.T, handler http.HandlerFunc, policy string) (*Scorer, func()) {
+	srv := httptest.NewServer(handler)
+	client, err := extscorer.NewClient(extscorer.Config{URL: srv.URL, Timeout: 50 * time.Millisecond, FailurePolicy: policy})
+	if err != nil {
+		srv.Close()
+		t.Fatalf("NewClient: %v", err)
+	}
+	return &Scorer{target: "default", externalScorer: client}, srv.Close
+}
+
+func respond(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+}
+
+func TestApplyExternalScorerMerge(t *testing.T) {
+	decisions := `{"decisions": {
+		"asg-a": {"bonus": 20},
+		"asg-b": {"bonus": 5, "malus": 60},
+		"asg-c": {"veto": true, "reason": "maintenance"}
+	}}`
+	tests := []struct {
+		name       string
+		disabled   []string
+		priorities map[int][]string
+		bonus      []int
+		malus      []int
+	}{
+		{
+			name:       "bonus, malus and veto",
+			priorities: map[int][]string{70: {"asg-a"}, 0: {"asg-b"}},
+			bonus:      []int{20, 5, 0},
+			malus:      []int{0, 60, 0},
+		},
+		{
+			name:       "malus disabled",
+			disabled:   []string{"external_malus"},
+			priorities: map[int][]string{70: {"asg-a"}, 45: {"asg-b"}},
+			bonus:      []int{20, 5, 0},
+			malus:      []int{0, 0, 0},
+		},
+		{
+			name:       "bonus disabled",
+			disabled:   []string{"external_bonus"},
+			priorities: map[int][]string{50: {"asg-a"}, 0: {"asg-b"}},
+			bonus:      []int{0, 0, 0},
+			malus:      []int{0, 60, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, stop := newExternalScorer(t, respond(decisions), extscorer.FailOpen)
+			defer stop()
+
+			snap, result := newExternalResult()
+			cfg := config.ScorerConfiguration{DisabledComponents: tt.disabled}
+			if err := s.applyExternalScorer(snap, cfg, Hints{}, result); err != nil {
+				t.Fatalf("applyExternalScorer() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Priorities, tt.priorities) {
+				t.Errorf("priorities = %v, want %v", result.Priorities, tt.priorities)
+			}
+			for i, b := range result.Breakdown {
+				if b.ExternalBonus != tt.bonus[i] || b.ExternalMalus != tt.malus[i] {
+					t.Errorf("%s: external bonus %d, malus %d, want %d, %d", b.ASGName, b.ExternalBonus, b.ExternalMalus, tt.bonus[i], tt.malus[i])
+				}
+			}
+			if c := result.Breakdown[2]; !c.Vetoed || c.ExternalReason != "maintenance" {
+				t.Errorf("asg-c is not vetoed: %+v", c)
+			}
+		})
+	}
+}
+
+func TestApplyExternalScorerFailures(t *testing.T) {
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		respond(`{"decisions": {"asg-a": {"veto": true}}}`)(w, r)
+	}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		policy  string
+		wantErr bool
+	}{
+		{name: "timeout, fail open", handler: slow, policy: extscorer.FailOpen},
+		{name: "timeout, fail closed", handler: slow, policy: extscorer.FailClosed, wantErr: true},
+		{name: "malformed response, fail open", handler: respond(`{"decisions": `), policy: extscorer.FailOpen},
+		{name: "malformed response, fail closed", handler: respond(`{"decisions": `), policy: extscorer.FailClosed, wantErr: true},
+		{name: "negative malus, fail closed", handler: respond(`{"decisions": {"asg-a": {"malus": -1}}}`), policy: extscorer.FailClosed, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, stop := newExternalScorer(t, tt.handler, tt.policy)
+			defer stop()
+
+			snap, result := newExternalResult()
+			_, want := newExternalResult()
+			err := s.applyExternalScorer(snap, config.ScorerConfiguration{}, Hints{}, result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyExternalScorer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "priorities not updated") {
+				t.Errorf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(result.Priorities, want.Priorities) || !reflect.DeepEqual(result.Breakdown, want.Breakdown) {
+				t.Errorf("the result changed after a failure: %v", result.Priorities)
+			}
+		})
+	}
+}
+
+func TestApplyExternalScorerDisabled(t *testing.T) {
+	called := false
+	s, stop := newExternalScorer(t, func(w http.ResponseWriter, r *http.Request) { called = true }, extscorer.FailClosed)
+	defer stop()
+
+	snap, result := newExternalResult()
+	cfg := config.ScorerConfiguration{DisabledComponents: []string{"external_bonus", "external_malus"}}
+	if err := s.applyExternalScorer(snap, cfg, Hints{}, result); err != nil {
+		t.Fatalf("applyExternalScorer() error = %v", err)
+	}
+	if called {
+		t.Errorf("the external scorer was called with both components disabled")
+	}
+}
@@ -1,9 +1,11 @@
 package scorer
 
 import (
-	"gopkg.in/yaml.v2"
+	"fmt"
 	"regexp"
 
+	"gopkg.in/yaml.v2"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -23,18 +25,19 @@ type Hints struct {
 	priorities   map[int][]string
 }
 
-func parseHintsString(yamlString string) (hints map[int][]*regexp.Regexp) {
+func parseHintsString(yamlString string) (hints map[int][]*regexp.Regexp, err error) {
 	var res map[int][]string
 	hints = make(map[int][]*regexp.Regexp)
-	if err := yaml.Unmarshal([]byte(yamlString), &res); err != nil {
+	if err = yaml.Unmarshal([]byte(yamlString), &res); err != nil {
 		klog.Errorf("Can't parse YAML with hints: %v - error: %v", yamlString, err)
 		return
 	}
 	for prio, reList := range res {
 		for _, re := range reList {
-			regexp, err := regexp.Compile(re)
-			if err != nil {
-				klog.Errorf("Can't compile regexp rule for priority %d and rule %s: %v", prio, re, err)
+			regexp, cErr := regexp.Compile(re)
+			if cErr != nil {
+				err = fmt.Errorf("Can't compile regexp rule for priority %d and rule %s: %v", prio, re, cErr)
+				klog.Errorf(err.Error())
 				return
 			}
 			hints[prio] = append(hints[prio], regexp)
@@ -46,9 +49,12 @@ func parseHintsString(yamlString string) (hints map[int][]*regexp.Regexp) {
 func (s *Scorer) getOrCreateHints() error {
 	var bonusString, malusString, prioString string
 	var found bool
+	var hints Hints
+	var parseErrors []string
 	needsUpdate := false
+	hintsConfigMapName := s.hintsConfigMapName()
 
-	cm, err := s.cmLister.ConfigMaps(s.namespace).Get(s.config.HintsConfigMapName)
+	cm, err := s.cmLister.ConfigMaps(s.namespace).Get(hintsConfigMapName)
 	if err == nil {
 		bonusString, found = cm.Data[bonusKey]
 		if !found {
@@ -56,7 +62,9 @@ func (s *Scorer) getOrCreateHints() error {
 			cm.Data[bonusKey] = bonusString
 			needsUpdate = true
 		}
-		s.hints.bonus = parseHintsString(bonusString)
+		if hints.bonus, err = parseHintsString(bonusString); err != nil {
+			parseErrors = append(parseErrors, fmt.Sprintf("%s: %v", bonusKey, err))
+		}
 
 		malusString, found = cm.Data[malusKey]
 		if !found {
@@ -64,7 +72,9 @@ func (s *Scorer) getOrCreateHints() error {
 			cm.Data[malusKey] = malusString
 			needsUpdate = true
 		}
-		s.hints.malus = parseHintsString(malusString)
+		if hints.malus, err = parseHintsString(malusString); err != nil {
+			parseErrors = append(parseErrors, fmt.Sprintf("%s: %v", malusKey, err))
+		}
 
 		prioString, found = cm.Data[prioKey]
 		if !found {
@@ -72,15 +82,19 @@ func (s *Scorer) getOrCreateHints() error {
 			cm.Data[prioKey] = prioString
 			needsUpdate = true
 		}
-		// reset the hinted priorities map
-		s.hints.priorities = nil
-		if err := yaml.Unmarshal([]byte(prioString), &s.hints.priorities); err != nil {
+		if err := yaml.Unmarshal([]byte(prioString), &hints.priorities); err != nil {
 			klog.Errorf("Can't parse YAML with hinted priorities in the configmap: %v", err)
+			parseErrors = append(parseErrors, fmt.Sprintf("%s: %v", prioKey, err))
 		}
+		s.reportHintsErrors(parseErrors)
+
+		s.dataMu.Lock()
+		s.hints = hints
+		s.dataMu.Unlock()
 	} else {
 		statusErr, ok := err.(*errors.StatusError)
 		if !ok {
-			klog.Errorf("Error getting %s/%s config map: %v", s.namespace, s.config.HintsConfigMapName, err)
+			klog.Errorf("Error getting %s/%s config map: %v", s.namespace, hintsConfigMapName, err)
 			return err
 		}
 		if statusErr.Status().Reason == metav1.StatusReasonNotFound {
@@ -88,7 +102,7 @@ func (s *Scorer) getOrCreateHints() error {
 				Create(&corev1.ConfigMap{
 					ObjectMeta: metav1.ObjectMeta{
 						Namespace: s.namespace,
-						Name:      s.config.HintsConfigMapName,
+						Name:      hintsConfigMapName,
 					},
 					Data: map[string]string{
 						"bonus":      "{}",
@@ -97,12 +111,12 @@ func (s *Scorer) getOrCreateHints() error {
 					},
 				})
 			if err != nil {
-				klog.Errorf("Error creating %s/%s config map: %v", s.namespace, s.config.HintsConfigMapName, err)
+				klog.Errorf("Error creating %s/%s config map: %v", s.namespace, hintsConfigMapName, err)
 				return err
 			}
 			return nil
 		} else {
-			klog.Errorf("Error getting %s/%s config map: %v", s.namespace, s.config.HintsConfigMapName, err)
+			klog.Errorf("Error getting %s/%s config map: %v", s.namespace, hintsConfigMapName, err)
 			return err
 		}
 	}
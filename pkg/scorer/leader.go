@@ -8,6 +8,8 @@ import (
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
 )
 
 const leaderLeaseName = "cluster-autoscaler-priority-helper-leader-lease"
@@ -26,11 +28,19 @@ func getIdentity() string {
 	return identity
 }
 
+// leaderLeaseNameFor returns the lease name of the target, every target has its own leader election
+func leaderLeaseNameFor(target string) string {
+	if target == config.DefaultTargetName {
+		return leaderLeaseName
+	}
+	return fmt.Sprintf("%s-%s", leaderLeaseName, target)
+}
+
 func getLeaderLock(s *Scorer) resourcelock.Interface {
 	lock, err := resourcelock.New(
 		s.lec.ResourceLock,
 		s.namespace,
-		leaderLeaseName,
+		leaderLeaseNameFor(s.target),
 		s.clientset.CoreV1(),
 		s.clientset.CoordinationV1(),
 		resourcelock.ResourceLockConfig{
@@ -0,0 +1,232 @@
+package scorer
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/fetcher"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+// The configuration, the outputs and the refresh interval can be changed on a running
+// scorer (configuration file reload, profiles or PriorityHelperConfig resource), the priorities
+// are recomputed right away when leading. The effective configuration is the base one with
+// the overlay of the active profile and then the one of the PriorityHelperConfig resource.
+
+func (s *Scorer) notifyConfigChanged() {
+	select {
+	case s.configChangedCh <- struct{}{}:
+	default:
+	}
+}
+
+// SetConfig replaces the base scorer configuration
+func (s *Scorer) SetConfig(cfg config.ScorerConfiguration) {
+	s.dataMu.Lock()
+	s.baseConfig = cfg
+	effective, err := overlayConfig(cfg, s.profileOverlay, s.resourceOverlay)
+	if err != nil {
+		klog.Errorf("Configuration overlays of target %s are not valid anymore, ignoring them: %v", s.target, err)
+		effective = cfg
+	}
+	changed := s.applyConfigLocked(effective)
+	s.dataMu.Unlock()
+	if changed {
+		s.notifyConfigChanged()
+	}
+}
+
+// SetConfigOverlay sets a JSON document with the fields of the scorer configuration to
+// override on top of the base one and of the profile, an empty overlay removes it.
+// The overlay is not applied when the resulting configuration is not valid.
+func (s *Scorer) SetConfigOverlay(overlay []byte) error {
+	s.dataMu.Lock()
+	if bytes.Equal(s.resourceOverlay, overlay) {
+		s.dataMu.Unlock()
+		return nil
+	}
+	effective, err := overlayConfig(s.baseConfig, s.profileOverlay, overlay)
+	if err != nil {
+		s.dataMu.Unlock()
+		return err
+	}
+	s.resourceOverlay = overlay
+	changed := s.applyConfigLocked(effective)
+	s.dataMu.Unlock()
+	if changed {
+		s.notifyConfigChanged()
+	}
+	return nil
+}
+
+// ValidateConfigOverlay checks, w/o applying it, that the configuration resulting from the
+// overlay (see SetConfigOverlay) is valid.
+func (s *Scorer) ValidateConfigOverlay(overlay []byte) error {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+	_, err := overlayConfig(s.baseConfig, s.profileOverlay, overlay)
+	return err
+}
+
+// SetProfile activates a named profile, a JSON document with the fields of the scorer configuration
+// to override on top of the base one, the empty name goes back to the base configuration.
+// The profile is not applied when the resulting configuration is not valid.
+func (s *Scorer) SetProfile(name string, overlay []byte) error {
+	s.dataMu.Lock()
+	effective, err := overlayConfig(s.baseConfig, overlay, s.resourceOverlay)
+	if err != nil {
+		s.dataMu.Unlock()
+		return err
+	}
+	previous := s.profile
+	s.profile, s.profileOverlay = name, overlay
+	changed := s.applyConfigLocked(effective) || previous != name
+	s.dataMu.Unlock()
+	if previous != name {
+		klog.Infof("Profile of target %s changed from %q to %q", s.target, previous, name)
+		s.emitEvent(corev1.EventTypeNormal, reasonProfileChanged, "Profile changed from %q to %q", previous, name)
+	}
+	if changed {
+		s.notifyConfigChanged()
+	}
+	return nil
+}
+
+// Profile returns the name of the active profile, empty when there is none
+func (s *Scorer) Profile() string {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+	return s.profile
+}
+
+func overlayConfig(base config.ScorerConfiguration, overlays ...[]byte) (config.ScorerConfiguration, error) {
+	cfg := base
+	for _, overlay := range overlays {
+		if len(overlay) == 0 {
+			continue
+		}
+		if err := config.Overlay(&cfg, overlay); err != nil {
+			return base, err
+		}
+	}
+	return cfg, config.Validate(cfg)
+}
+
+func (s *Scorer) applyConfigLocked(cfg config.ScorerConfiguration) bool {
+	if reflect.DeepEqual(s.config, cfg) {
+		return false
+	}
+	s.config = cfg
+	klog.Infof("Scorer configuration of target %s changed", s.target)
+	return true
+}
+
+// Config returns the current scorer configuration
+func (s *Scorer) Config() config.ScorerConfiguration {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+	return s.config
+}
+
+func (s *Scorer) hintsConfigMapName() string {
+	return s.Config().HintsConfigMapName
+}
+
+// SetOutputConfigMaps changes the output ConfigMap and the breakdown one (empty to disable it),
+// the previous ConfigMaps are left as they are.
+func (s *Scorer) SetOutputConfigMaps(outConfigMapName, breakdownConfigMapName string) error {
+	if outConfigMapName == "" {
+		return fmt.Errorf("output ConfigMap is mandatory")
+	}
+	s.dataMu.Lock()
+	if s.outConfigMapName == outConfigMapName && s.breakdownConfigMapName == breakdownConfigMapName {
+		s.dataMu.Unlock()
+		return nil
+	}
+	s.outConfigMapName, s.breakdownConfigMapName = outConfigMapName, breakdownConfigMapName
+	s.dataMu.Unlock()
+	klog.Infof("Output ConfigMaps of target %s changed to %q and %q", s.target, outConfigMapName, breakdownConfigMapName)
+	s.notifyConfigChanged()
+	return nil
+}
+
+// OutputConfigMaps returns the names of the output and breakdown (empty when disabled) ConfigMaps
+func (s *Scorer) OutputConfigMaps() (string, string) {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+	return s.outConfigMapName, s.breakdownConfigMapName
+}
+
+// SetRefreshInterval changes the interval of the periodic update of the output ConfigMap
+func (s *Scorer) SetRefreshInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("refresh interval has to be positive")
+	}
+	s.dataMu.Lock()
+	if s.refreshInterval == interval {
+		s.dataMu.Unlock()
+		return nil
+	}
+	s.refreshInterval = interval
+	s.dataMu.Unlock()
+	klog.Infof("Refresh interval of target %s changed to %s", s.target, interval)
+	s.notifyConfigChanged()
+	return nil
+}
+
+func (s *Scorer) RefreshInterval() time.Duration {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+	return s.refreshInterval
+}
+
+func (s *Scorer) setLastSuccessfulUpdate() {
+	s.dataMu.Lock()
+	defer s.dataMu.Unlock()
+	s.lastSuccessfulUpdate = time.Now()
+}
+
+func (s *Scorer) setHeartbeat() {
+	s.dataMu.Lock()
+	defer s.dataMu.Unlock()
+	s.lastHeartbeat = time.Now()
+}
+
+// LastSuccessfulUpdate returns when the output ConfigMap was last updated or found up to date
+func (s *Scorer) LastSuccessfulUpdate() time.Time {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+	return s.lastSuccessfulUpdate
+}
+
+// SourceHealth is the state of a data source as seen by the scorer
+type SourceHealth struct {
+	Name      string `json:"name"`
+	Synced    bool   `json:"synced"`
+	Failures  int    `json:"failures"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+// SourcesHealth returns the state of the data sources, sorted by name
+func (s *Scorer) SourcesHealth() []SourceHealth {
+	res := []SourceHealth{{Name: "nodes", Synced: s.nodesDistribution.HasSynced()}}
+	s.failingSourcesMu.Lock()
+	for _, dm := range []*fetcher.DataManager{s.spotAdvisor.DataManager, s.pricer.DataManager, s.asgDiscoverer.DataManager} {
+		name := dm.GetName()
+		res = append(res, SourceHealth{
+			Name:      name,
+			Synced:    dm.HasSynced(),
+			Failures:  s.sourceFailures[name],
+			LastError: s.sourceLastErrors[name],
+		})
+	}
+	s.failingSourcesMu.Unlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
@@ -0,0 +1,36 @@
+package scorer
+
+import (
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/utils"
+)
+
+// capacityFor returns the capacity of the instance type the price is divided by, with the unit of
+// the compared price, the capacity is unknown when the spot advisor has no data for the instance type.
+func capacityFor(snap *Snapshot, cfg config.ScorerConfiguration, region, instanceType string) (float64, string, bool) {
+	cores := float64(snap.GetCoresFor(region, instanceType))
+	memory := snap.GetMemoryFor(region, instanceType)
+	switch cfg.PriceNormalization {
+	case config.PriceNormalizationVCPU:
+		return cores, "vcpu-hour", cores > 0
+	case config.PriceNormalizationMemory:
+		return memory, "gib-hour", memory > 0
+	case config.PriceNormalizationWeighted:
+		capacity := cores*cfg.PriceVCPUWeight + memory*cfg.PriceMemoryWeight
+		return capacity, "unit-hour", cores > 0 && memory > 0 && capacity > 0
+	}
+	return 1, "hour", true
+}
+
+// spotPriceRatio returns the ratio between the spot and the on-demand price of the instance type
+func spotPriceRatio(snap *Snapshot, iDetails utils.InstanceDetails) (float64, bool) {
+	spot, found := snap.GetPriceFor(iDetails.InstanceType, iDetails.AvailabilityZone, true)
+	if !found {
+		return 0, false
+	}
+	onDemand, found := snap.GetPriceFor(iDetails.InstanceType, iDetails.AvailabilityZone, false)
+	if !found || onDemand <= 0 {
+		return 0, false
+	}
+	return spot / onDemand, true
+}
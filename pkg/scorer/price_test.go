@@ -0,0 +1,96 @@
+package scorer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+func TestCapacityFor(t *testing.T) {
+	snap := NewSnapshot(time.Now())
+	snap.Advisor[advisorKey("eu-west-1", "m5.xlarge")] = AdvisorData{Cores: 4, Memory: 16}
+	snap.Advisor[advisorKey("eu-west-1", "m5.nomem")] = AdvisorData{Cores: 4}
+	tests := []struct {
+		name          string
+		normalization string
+		instanceType  string
+		capacity      float64
+		unit          string
+		known         bool
+	}{
+		{name: "none", instanceType: "x9.large", capacity: 1, unit: "hour", known: true},
+		{name: "vcpu", normalization: config.PriceNormalizationVCPU, instanceType: "m5.xlarge", capacity: 4, unit: "vcpu-hour", known: true},
+		{name: "memory", normalization: config.PriceNormalizationMemory, instanceType: "m5.xlarge", capacity: 16, unit: "gib-hour", known: true},
+		{name: "weighted", normalization: config.PriceNormalizationWeighted, instanceType: "m5.xlarge", capacity: 4*1 + 16*0.25, unit: "unit-hour", known: true},
+		{name: "vcpu unknown", normalization: config.PriceNormalizationVCPU, instanceType: "x9.large", unit: "vcpu-hour"},
+		{name: "memory unknown", normalization: config.PriceNormalizationMemory, instanceType: "m5.nomem", unit: "gib-hour"},
+		{name: "weighted w/o memory", normalization: config.PriceNormalizationWeighted, instanceType: "m5.nomem", unit: "unit-hour"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.ScorerConfiguration{PriceNormalization: tt.normalization, PriceVCPUWeight: 1, PriceMemoryWeight: 0.25}
+			capacity, unit, known := capacityFor(snap, cfg, "eu-west-1", tt.instanceType)
+			if unit != tt.unit || known != tt.known || (known && capacity != tt.capacity) {
+				t.Errorf("capacityFor() = %v, %s, %v, want %v, %s, %v", capacity, unit, known, tt.capacity, tt.unit, tt.known)
+			}
+		})
+	}
+}
+
+// comparedPrice is the price an ASG is compared with, its unit and the resulting malus
+type comparedPrice struct {
+	price float64
+	unit  string
+	malus int
+}
+
+func TestComparedPrice(t *testing.T) {
+	snap := NewSnapshot(time.Now())
+	for _, it := range []string{"m5.xlarge", "x9.xlarge"} {
+		snap.ASGs[it] = ASGSnapshot{InstanceTypes: []string{it}, AvailabilityZone: "eu-west-1a"}
+		snap.OnDemandPrices[priceKey(it, "eu-west-1a", false)] = 0.2
+	}
+	snap.Advisor[advisorKey("eu-west-1", "m5.xlarge")] = AdvisorData{Cores: 4, Memory: 16}
+	// the capacity of x9.xlarge is unknown, its hourly price is compared
+	hourly := comparedPrice{0.2, "hour", 20}
+	tests := []struct {
+		name          string
+		normalization string
+		mode          string
+		known         comparedPrice
+		unknown       comparedPrice
+	}{
+		{name: "absolute", known: hourly, unknown: hourly},
+		{name: "vcpu", normalization: config.PriceNormalizationVCPU, known: comparedPrice{0.05, "vcpu-hour", 5}, unknown: hourly},
+		{name: "memory", normalization: config.PriceNormalizationMemory, known: comparedPrice{0.0125, "gib-hour", 1}, unknown: hourly},
+		{name: "weighted", normalization: config.PriceNormalizationWeighted, known: comparedPrice{0.025, "unit-hour", 2}, unknown: hourly},
+		{
+			name: "vcpu ranked", normalization: config.PriceNormalizationVCPU, mode: config.PriceModeRank,
+			known: comparedPrice{0.05, "vcpu-hour", 0}, unknown: comparedPrice{0.2, "hour", 100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.ScorerConfiguration{
+				BasePriority: 1000, MalusForPrice: 100, PriceMode: tt.mode,
+				PriceNormalization: tt.normalization, PriceVCPUWeight: 1, PriceMemoryWeight: 0.25,
+			}
+			result := computeScores(snap, cfg, Hints{})
+			for _, b := range result.Breakdown {
+				want, fallback := tt.known, false
+				if b.ASGName == "x9.xlarge" {
+					want, fallback = tt.unknown, tt.normalization != ""
+				}
+				got := comparedPrice{b.ComparedPrice, b.PriceUnit, b.PriceMalus}
+				if math.Abs(got.price-want.price) > 1e-9 || got.unit != want.unit || got.malus != want.malus {
+					t.Errorf("%s: compared price %+v, want %+v", b.ASGName, got, want)
+				}
+				if b.PriceNotNormalized != fallback {
+					t.Errorf("%s: priceNotNormalized = %v, want %v", b.ASGName, b.PriceNotNormalized, fallback)
+				}
+			}
+		})
+	}
+}
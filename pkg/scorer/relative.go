@@ -0,0 +1,96 @@
+package scorer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+// relativeMalus returns the malus of a value compared with the values of its group, from zero for
+// the lowest to max for the highest, by rank (the same values have the same rank) or min-max normalized.
+func relativeMalus(values []float64, value float64, mode string, max int) int {
+	if mode == config.PriceModeMinMax {
+		lowest, highest := values[0], values[0]
+		for _, v := range values {
+			lowest, highest = math.Min(lowest, v), math.Max(highest, v)
+		}
+		if highest == lowest {
+			return 0
+		}
+		return int(math.Round((value - lowest) / (highest - lowest) * float64(max)))
+	}
+	distinct := []float64{}
+	seen := make(map[float64]bool)
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			distinct = append(distinct, v)
+		}
+	}
+	if len(distinct) < 2 {
+		return 0
+	}
+	sort.Float64s(distinct)
+	rank := sort.SearchFloat64s(distinct, value)
+	return int(math.Round(float64(rank) / float64(len(distinct)-1) * float64(max)))
+}
+
+// relativeScope returns the group of the ASGs whose prices are compared with the one of the ASG
+func relativeScope(cfg config.ScorerConfiguration, b ScoreBreakdown) string {
+	switch cfg.PriceScope {
+	case config.PriceScopePool:
+		return fmt.Sprintf("pool:%s", b.Pool)
+	case config.PriceScopeVCPU:
+		return fmt.Sprintf("vcpu:%d", b.VCPUs)
+	}
+	return "all"
+}
+
+// applyRelativeMaluses is the second pass of the relative price modes and of the expected-cost model,
+// every ASG gets a malus relative to the price (or expected cost) of the other ASGs of its scope.
+// The priorities are not floored at zero, the hints are applied afterwards.
+func applyRelativeMaluses(result *Result, cfg config.ScorerConfiguration, pools []config.Pool) {
+	configs := configsByPool(cfg, pools)
+	type member struct {
+		b     *ScoreBreakdown
+		value float64
+		malus *int
+		max   int
+		mode  string
+	}
+	groups := make(map[string][]member)
+	for i := range result.Breakdown {
+		b := &result.Breakdown[i]
+		asgCfg := configs[b.Pool]
+		scope := relativeScope(asgCfg, *b)
+		switch {
+		case b.HasExpectedCost:
+			// the expected costs are ranked unless they are min-max normalized
+			mode := config.PriceModeRank
+			if asgCfg.PriceMode == config.PriceModeMinMax {
+				mode = config.PriceModeMinMax
+			}
+			groups["cost/"+scope] = append(groups["cost/"+scope],
+				member{b, b.ExpectedCost, &b.ExpectedCostMalus, asgCfg.MalusForExpectedCost, mode})
+		case b.HasRelativePrice:
+			groups["price/"+scope] = append(groups["price/"+scope],
+				member{b, b.ComparedPrice, &b.PriceMalus, asgCfg.MalusForPrice, asgCfg.PriceMode})
+		}
+	}
+	for scope, members := range groups {
+		values := make([]float64, 0, len(members))
+		for _, m := range members {
+			values = append(values, m.value)
+		}
+		for _, m := range members {
+			*m.malus = relativeMalus(values, m.value, m.mode, m.max)
+			m.b.Priority -= *m.malus
+			klog.V(3).Infof("Scorer compute priority for %s\t (%s %.4f among %d, %s) prio-=%d (prio=%d)",
+				m.b.ASGName, scope, m.value, len(members), m.mode, *m.malus, m.b.Priority)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package scorer
+
+import (
+	"testing"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+func TestRelativeMalus(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []float64
+		mode    string
+		maluses []int
+	}{
+		{name: "rank", values: []float64{0.1, 0.3, 0.2}, mode: config.PriceModeRank, maluses: []int{0, 100, 50}},
+		{name: "rank with ties", values: []float64{0.1, 0.3, 0.1, 0.2}, mode: config.PriceModeRank, maluses: []int{0, 100, 0, 50}},
+		{name: "rank of equal values", values: []float64{0.2, 0.2}, mode: config.PriceModeRank, maluses: []int{0, 0}},
+		{name: "min-max", values: []float64{0.1, 0.5, 0.2}, mode: config.PriceModeMinMax, maluses: []int{0, 100, 25}},
+		{name: "min-max with ties", values: []float64{0.1, 0.5, 0.5}, mode: config.PriceModeMinMax, maluses: []int{0, 100, 100}},
+		{name: "min-max of equal values", values: []float64{0.2, 0.2}, mode: config.PriceModeMinMax, maluses: []int{0, 0}},
+		{name: "single value", values: []float64{0.2}, mode: config.PriceModeRank, maluses: []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, v := range tt.values {
+				if malus := relativeMalus(tt.values, v, tt.mode, 100); malus != tt.maluses[i] {
+					t.Errorf("relativeMalus(%.2f) = %d, want %d", v, malus, tt.maluses[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyRelativeMaluses(t *testing.T) {
+	// a and b are in the pool, c and d are not; a and c have 2 vCPUs, b and d 4
+	breakdowns := func() []ScoreBreakdown {
+		return []ScoreBreakdown{
+			{ASGName: "a", Pool: "batch", VCPUs: 2, ComparedPrice: 0.1, ExpectedCost: 0.1},
+			{ASGName: "b", Pool: "batch", VCPUs: 4, ComparedPrice: 0.4, ExpectedCost: 0.4},
+			{ASGName: "c", VCPUs: 2, ComparedPrice: 0.2, ExpectedCost: 0.1},
+			{ASGName: "d", VCPUs: 4, ComparedPrice: 0.3, ExpectedCost: 0.3},
+		}
+	}
+	tests := []struct {
+		name         string
+		cfg          config.ScorerConfiguration
+		expectedCost bool
+		maluses      []int
+	}{
+		{
+			name:    "rank among all",
+			cfg:     config.ScorerConfiguration{PriceMode: config.PriceModeRank, PriceScope: config.PriceScopeAll},
+			maluses: []int{0, 90, 30, 60},
+		},
+		{
+			name:    "min-max among all",
+			cfg:     config.ScorerConfiguration{PriceMode: config.PriceModeMinMax, PriceScope: config.PriceScopeAll},
+			maluses: []int{0, 90, 30, 60},
+		},
+		{
+			name:    "rank by pool",
+			cfg:     config.ScorerConfiguration{PriceMode: config.PriceModeRank, PriceScope: config.PriceScopePool},
+			maluses: []int{0, 90, 0, 90},
+		},
+		{
+			name:    "rank by vCPU class",
+			cfg:     config.ScorerConfiguration{PriceMode: config.PriceModeRank, PriceScope: config.PriceScopeVCPU},
+			maluses: []int{0, 90, 90, 0},
+		},
+		{
+			name:         "expected cost with tied costs",
+			cfg:          config.ScorerConfiguration{PriceScope: config.PriceScopeAll},
+			expectedCost: true,
+			maluses:      []int{0, 300, 0, 150},
+		},
+		{
+			name:         "expected cost min-max by vCPU class with tied costs",
+			cfg:          config.ScorerConfiguration{PriceMode: config.PriceModeMinMax, PriceScope: config.PriceScopeVCPU},
+			expectedCost: true,
+			maluses:      []int{0, 300, 0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.MalusForPrice, tt.cfg.MalusForExpectedCost = 90, 300
+			pools := []config.Pool{{Name: "batch", Config: tt.cfg}}
+			result := &Result{Breakdown: breakdowns()}
+			for i := range result.Breakdown {
+				b := &result.Breakdown[i]
+				b.Priority = 50
+				b.HasExpectedCost, b.HasRelativePrice = tt.expectedCost, !tt.expectedCost
+			}
+			applyRelativeMaluses(result, tt.cfg, pools)
+			for i, b := range result.Breakdown {
+				malus := b.PriceMalus
+				if tt.expectedCost {
+					malus = b.ExpectedCostMalus
+				}
+				if malus != tt.maluses[i] {
+					t.Errorf("%s: malus = %d, want %d", b.ASGName, malus, tt.maluses[i])
+				}
+				// the priorities are not floored, the hints are applied afterwards
+				if b.Priority != 50-tt.maluses[i] {
+					t.Errorf("%s: priority = %d, want %d", b.ASGName, b.Priority, 50-tt.maluses[i])
+				}
+			}
+		})
+	}
+}
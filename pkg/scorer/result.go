@@ -0,0 +1,109 @@
+package scorer
+
+import (
+	"time"
+)
+
+// ScoreBreakdown describes how the priority of an ASG was computed,
+// maluses are reported as positive values that are subtracted.
+type ScoreBreakdown struct {
+	ASGName          string   `json:"asgName"`
+	Name             string   `json:"name"`
+	Pool             string   `json:"pool,omitempty"`
+	InstanceType     string   `json:"instanceType"`
+	InstanceTypes    []string `json:"instanceTypes"`
+	AvailabilityZone string   `json:"availabilityZone"`
+	IsSpot           bool     `json:"isSpot"`
+
+	Price       float64 `json:"price"`
+	HasPrice    bool    `json:"hasPrice"`
+	Probability float64 `json:"probability"`
+	NodeCount   int     `json:"nodeCount"`
+	// Savings is the spot advisor savings over on-demand (percentage) of the spot ASGs, -1 when unknown
+	Savings float64 `json:"savings,omitempty"`
+	// SpotPriceRatio is the spot price over the on-demand one of the spot ASGs, ExpensiveSpot is set
+	// when it is above the configured threshold
+	SpotPriceRatio float64 `json:"spotPriceRatio,omitempty"`
+	ExpensiveSpot  bool    `json:"expensiveSpot,omitempty"`
+	// ComparedPrice is the price per PriceUnit (hour, vcpu-hour, gib-hour or unit-hour) used by the price scoring
+	ComparedPrice float64 `json:"comparedPrice,omitempty"`
+	PriceUnit     string  `json:"priceUnit,omitempty"`
+	// PriceNotNormalized is set when the price should be normalized but the capacity of the instance type
+	// is unknown, the hourly price is compared instead
+	PriceNotNormalized bool `json:"priceNotNormalized,omitempty"`
+	// HasRelativePrice is set when the price malus is relative to the other ASGs (see config.PriceModeRank)
+	HasRelativePrice bool `json:"hasRelativePrice,omitempty"`
+	VCPUs            int  `json:"vcpus,omitempty"`
+	// ExpectedCost is the price plus the expected cost of the interruptions, with the expected-cost model
+	ExpectedCost    float64 `json:"expectedCost,omitempty"`
+	HasExpectedCost bool    `json:"hasExpectedCost,omitempty"`
+
+	Base                  int `json:"base"`
+	SpotBonus             int `json:"spotBonus"`
+	SavingsBonus          int `json:"savingsBonus"`
+	ExpensiveSpotMalus    int `json:"expensiveSpotMalus"`
+	OnDemandMalus         int `json:"onDemandMalus"`
+	ProbabilityMalus      int `json:"probabilityMalus"`
+	NodeDistributionMalus int `json:"nodeDistributionMalus"`
+	PriceMalus            int `json:"priceMalus"`
+	ExpectedCostMalus     int `json:"expectedCostMalus"`
+	Expression            int `json:"expression"`
+	ExternalBonus         int `json:"externalBonus"`
+	ExternalMalus         int `json:"externalMalus"`
+	HintsBonus            int `json:"hintsBonus"`
+	HintsMalus            int `json:"hintsMalus"`
+
+	Priority int `json:"priority"`
+	// Vetoed ASGs are left out of the priorities by the external scorer
+	Vetoed         bool   `json:"vetoed,omitempty"`
+	ExternalReason string `json:"externalReason,omitempty"`
+
+	// Derived are the coefficients derived from the cost-vs-reliability and diversity dials
+	Derived map[string]int `json:"derived,omitempty"`
+}
+
+// Result is the outcome of a scores computation
+type Result struct {
+	Time       time.Time        `json:"time"`
+	Profile    string           `json:"profile,omitempty"`
+	Priorities map[int][]string `json:"priorities"`
+	Breakdown  []ScoreBreakdown `json:"breakdown"`
+}
+
+// ComponentNames are the keys returned by ScoreBreakdown.Components in the order they are applied
+var ComponentNames = []string{
+	"base",
+	"spot_bonus",
+	"savings_bonus",
+	"expensive_spot_malus",
+	"ondemand_malus",
+	"probability_malus",
+	"node_distribution_malus",
+	"price_malus",
+	"expected_cost_malus",
+	"expression",
+	"hints_bonus",
+	"hints_malus",
+	"external_bonus",
+	"external_malus",
+}
+
+// Components returns the points of every score component, maluses are negative
+func (b ScoreBreakdown) Components() map[string]int {
+	return map[string]int{
+		"base":                    b.Base,
+		"spot_bonus":              b.SpotBonus,
+		"savings_bonus":           b.SavingsBonus,
+		"expensive_spot_malus":    -b.ExpensiveSpotMalus,
+		"ondemand_malus":          -b.OnDemandMalus,
+		"probability_malus":       -b.ProbabilityMalus,
+		"node_distribution_malus": -b.NodeDistributionMalus,
+		"price_malus":             -b.PriceMalus,
+		"expected_cost_malus":     -b.ExpectedCostMalus,
+		"expression":              b.Expression,
+		"hints_bonus":             b.HintsBonus,
+		"hints_malus":             -b.HintsMalus,
+		"external_bonus":          b.ExternalBonus,
+		"external_malus":          -b.ExternalMalus,
+	}
+}
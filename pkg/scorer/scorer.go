@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -22,12 +23,16 @@ import (
 	clientset "k8s.io/client-go/kubernetes"
 	listers_v1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	componentbaseconfig "k8s.io/component-base/config"
 
 	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
 
 	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/aws"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/extscorer"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/metrics"
 	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/nodes"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/notifier"
 	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/spotadvisor"
 	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/utils"
 
@@ -40,21 +45,28 @@ type Patch struct {
 	Value interface{} `json:"value"`
 }
 
+// Runnable is started along with the data sources while the Scorer is running (leading)
+// and it has to stop when the stop channel is closed.
+type Runnable interface {
+	Start(stopCh <-chan struct{}) error
+}
+
 type Scorer struct {
 	ctx               context.Context
 	ctxCancel         context.CancelFunc
 	internalCtx       context.Context
 	internalCtxCancel context.CancelFunc
 	mu                sync.Mutex
+	running           int32
 	lec               componentbaseconfig.LeaderElectionConfiguration
+	target            string
+	cluster           string
 
-	clientset        clientset.Interface
-	factory          informers.SharedInformerFactory
-	outConfigMapName string
-	cmInformer       cache.SharedIndexInformer
-	cmLister         listers_v1.ConfigMapLister
-	namespace        string
-	refreshInterval  time.Duration
+	clientset  clientset.Interface
+	factory    informers.SharedInformerFactory
+	cmInformer cache.SharedIndexInformer
+	cmLister   listers_v1.ConfigMapLister
+	namespace  string
 
 	spotAdvisor       *spotadvisor.SpotAdvisor
 	asgDiscoverer     *aws.ASGDiscoverer
@@ -66,10 +78,43 @@ type Scorer struct {
 	asgDiscovererLastChanges     time.Time
 	pricerLastChanges            time.Time
 
+	runnables []Runnable
+
+	recorder         record.EventRecorder
+	failingSourcesMu sync.Mutex
+	failingSources   map[string]bool
+	sourceFailures   map[string]int
+	sourceLastErrors map[string]string
+	lastHintsErrors  string
+	expensiveSpots   map[string]bool
+	notifier         *notifier.Notifier
+	externalScorer   *extscorer.Client
+
 	lastChange time.Time
 
-	config config.ScorerConfiguration
-	hints  Hints
+	// configChangedCh triggers an update when the configuration is changed while running
+	configChangedCh chan struct{}
+
+	// dataMu protects the configuration, hints and lastResult that are also read by the HTTP handlers
+	// and that can be changed while running (see live.go), and the state of the events (see events.go)
+	dataMu     sync.RWMutex
+	config     config.ScorerConfiguration
+	hints      Hints
+	lastResult *Result
+
+	baseConfig       config.ScorerConfiguration
+	profile          string
+	profileOverlay   []byte
+	resourceOverlay  []byte
+	outConfigMapName string
+	// breakdownConfigMapName is optional, see SetBreakdownConfigMap
+	breakdownConfigMapName string
+	refreshInterval        time.Duration
+	lastSuccessfulUpdate   time.Time
+	// lastHeartbeat is taken by the update loop at every iteration, see Alive
+	lastHeartbeat time.Time
+
+	priorityChanges []PriorityChange
 }
 
 func NewScorer(
@@ -83,13 +128,14 @@ func NewScorer(
 	asgDiscoverer *aws.ASGDiscoverer,
 	nodesDistribution *nodes.NodesDistribution,
 	pricer *aws.Pricer,
-	config config.ScorerConfiguration,
+	cfg config.ScorerConfiguration,
 ) *Scorer {
 	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
 
 	ctx, ctxCancel := context.WithCancel(parentCtx)
-	return &Scorer{
+	s := &Scorer{
 		lec:               lec,
+		target:            config.DefaultTargetName,
 		ctx:               ctx,
 		ctxCancel:         ctxCancel,
 		clientset:         clientset,
@@ -103,8 +149,17 @@ func NewScorer(
 		asgDiscoverer:     asgDiscoverer,
 		nodesDistribution: nodesDistribution,
 		pricer:            pricer,
-		config:            config,
+		config:            cfg,
+		baseConfig:        cfg,
+		configChangedCh:   make(chan struct{}, 1),
+		failingSources:    make(map[string]bool),
+		sourceFailures:    make(map[string]int),
+		sourceLastErrors:  make(map[string]string),
 	}
+	spotAdvisor.AddFailureHandler(s.handleSourceFailure)
+	pricer.AddFailureHandler(s.handleSourceFailure)
+	asgDiscoverer.AddFailureHandler(s.handleSourceFailure)
+	return s
 }
 
 func (s *Scorer) Run() {
@@ -143,7 +198,10 @@ func (s *Scorer) Exit() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.ctxCancel()
-	<-s.internalCtx.Done()
+	// a standby scorer was never started
+	if s.internalCtx != nil {
+		<-s.internalCtx.Done()
+	}
 	time.Sleep(3 * time.Second)
 }
 
@@ -155,6 +213,11 @@ func (s *Scorer) Stop() {
 	s.internalCtxCancel()
 	s.internalCtx = nil
 	s.internalCtxCancel = nil
+	atomic.StoreInt32(&s.running, 0)
+	s.dataMu.Lock()
+	s.lastHeartbeat = time.Time{}
+	s.dataMu.Unlock()
+	metrics.SetLeader(s.target, false)
 }
 
 func (s *Scorer) Start() error {
@@ -166,8 +229,9 @@ func (s *Scorer) Start() error {
 	cmEventHandler := cache.FilteringResourceEventHandler{
 		FilterFunc: func(obj interface{}) bool {
 			if cm, ok := obj.(*corev1.ConfigMap); ok {
-				return cm.ObjectMeta.Name == s.outConfigMapName ||
-					cm.ObjectMeta.Name == s.config.HintsConfigMapName
+				outConfigMapName, _ := s.OutputConfigMaps()
+				return cm.ObjectMeta.Name == outConfigMapName ||
+					cm.ObjectMeta.Name == s.hintsConfigMapName()
 			}
 			return false
 		},
@@ -176,7 +240,7 @@ func (s *Scorer) Start() error {
 				if cm, ok := obj.(*corev1.ConfigMap); ok {
 					klog.V(3).Infof("Updating config map because it (%s) was changed, last update was at %s",
 						cm.ObjectMeta.Name, s.lastChange)
-					if err := s.updateConfigMap(); err != nil {
+					if err := s.updateConfigMap(causeConfigMapChanged); err != nil {
 						klog.Errorf("Error udating config map because of configmap changes: %v", err)
 					}
 				} else {
@@ -185,6 +249,8 @@ func (s *Scorer) Start() error {
 			},
 		},
 	}
+	atomic.StoreInt32(&s.running, 1)
+	metrics.SetLeader(s.target, true)
 	s.cmInformer.AddEventHandler(cmEventHandler)
 	s.factory.Start(stopCh)
 	for _, ok := range s.factory.WaitForCacheSync(stopCh) {
@@ -196,12 +262,14 @@ func (s *Scorer) Start() error {
 	changesCh := make(chan struct{})
 	s.internalCtx, s.internalCtxCancel = context.WithCancel(s.ctx)
 	internalStopCh := s.internalCtx.Done()
-	ticker := time.NewTicker(s.refreshInterval)
+	refreshInterval := s.RefreshInterval()
+	ticker := time.NewTicker(refreshInterval)
 	go func() {
 		klog.V(2).Infof("Scorer go routine started, changes channel at %p", changesCh)
-		defer ticker.Stop()
-		defer close(changesCh)
+		// changesCh is not closed, the data sources can be shared and still notifying
+		defer func() { ticker.Stop() }()
 		for {
+			s.setHeartbeat()
 			select {
 			case <-stopCh:
 				klog.V(1).Infof("Context canceled, exiting")
@@ -211,12 +279,22 @@ func (s *Scorer) Start() error {
 				return
 			case <-changesCh:
 				klog.V(3).Infof("Updating config map because of changes, last update was at %s", s.lastChange)
-				if err := s.updateConfigMap(); err != nil {
+				if err := s.updateConfigMap(causeDataChanged); err != nil {
 					klog.Errorf("Error udating config map because of changes: %v", err)
 				}
+			case <-s.configChangedCh:
+				if interval := s.RefreshInterval(); interval != refreshInterval {
+					ticker.Stop()
+					refreshInterval = interval
+					ticker = time.NewTicker(refreshInterval)
+				}
+				klog.V(3).Infof("Updating config map because the configuration changed, last update was at %s", s.lastChange)
+				if err := s.updateConfigMap(causeConfigChanged); err != nil {
+					klog.Errorf("Error udating config map because of configuration changes: %v", err)
+				}
 			case <-ticker.C:
 				klog.V(3).Infof("Updating config map because of refresh interval, last update was at %s", s.lastChange)
-				if err := s.updateConfigMap(); err != nil {
+				if err := s.updateConfigMap(causeRefreshInterval); err != nil {
 					klog.Errorf("Error udating config map because of refresh interval: %v", err)
 				}
 			}
@@ -235,15 +313,67 @@ func (s *Scorer) Start() error {
 	if err := s.asgDiscoverer.Start(internalStopCh, changesCh); err != nil {
 		return err
 	}
+	for _, r := range s.runnables {
+		if err := r.Start(internalStopCh); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (s *Scorer) getOrUpdateOutputConfigMapChecksum(yamlData []byte, checksum string) (string, error) {
+// SetTarget sets the name of the target the scorer computes the priorities for, it is used
+// in the metrics and, when it is not the default one, in the leader election lease name.
+// It has to be called before Run.
+func (s *Scorer) SetTarget(name string) {
+	s.target = name
+}
+
+func (s *Scorer) Target() string {
+	return s.target
+}
+
+// SetCluster sets the kubeconfig context of the cluster the scorer publishes the priorities to,
+// it is only informative (notifications). It has to be called before Run.
+func (s *Scorer) SetCluster(context string) {
+	s.cluster = context
+}
+
+// AddRunnable registers a Runnable to be started along with the data sources,
+// it has to be called before Run.
+func (s *Scorer) AddRunnable(r Runnable) {
+	s.runnables = append(s.runnables, r)
+}
+
+// LastResult returns the last computed priorities with their breakdown, nil if not computed yet
+func (s *Scorer) LastResult() *Result {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+	return s.lastResult
+}
+
+// TakeSnapshot returns a copy of the current data used to compute the scores
+func (s *Scorer) TakeSnapshot() *Snapshot {
+	snap := TakeSnapshot(s.asgDiscoverer, s.pricer, s.spotAdvisor, s.nodesDistribution)
+	// the pools with a node selector have their own nodes distribution
+	pools, _ := s.Config().ResolvePools()
+	for _, pool := range pools {
+		if pool.NodeSelector.Empty() {
+			continue
+		}
+		if snap.PoolNodes == nil {
+			snap.PoolNodes = make(map[string]nodes.Counts)
+		}
+		snap.PoolNodes[pool.Name] = nodes.Counts(s.nodesDistribution.GetDataFor(pool.NodeSelector))
+	}
+	return snap
+}
+
+func (s *Scorer) getOrUpdateOutputConfigMapChecksum(outConfigMapName string, yamlData []byte, checksum, profile string) (string, error) {
 	var oldChecksum string
 	var err error
 	var cm *corev1.ConfigMap
 
-	cm, err = s.cmLister.ConfigMaps(s.namespace).Get(s.outConfigMapName)
+	cm, err = s.cmLister.ConfigMaps(s.namespace).Get(outConfigMapName)
 	if err == nil {
 		currentPrioritiesStr, ok := cm.Data["priorities"]
 		if ok {
@@ -264,36 +394,88 @@ func (s *Scorer) getOrUpdateOutputConfigMapChecksum(yamlData []byte, checksum st
 			_, err := s.clientset.CoreV1().ConfigMaps(s.namespace).
 				Create(&corev1.ConfigMap{
 					ObjectMeta: metav1.ObjectMeta{
-						Namespace: s.namespace,
-						Name:      s.outConfigMapName,
+						Namespace:   s.namespace,
+						Name:        outConfigMapName,
+						Annotations: profileAnnotations(profile),
 					},
 					Data: map[string]string{
 						"priorities": string(yamlData),
 					},
 				})
 			if err != nil {
-				klog.Errorf("Error creating %s/%s config map: %v", s.namespace, s.outConfigMapName, err)
+				klog.Errorf("Error creating %s/%s config map: %v", s.namespace, outConfigMapName, err)
 				return oldChecksum, err
 			}
 			s.lastChange = time.Now()
 			return oldChecksum, nil
 		} else {
-			klog.Errorf("Error getting %s/%s config map: %v", s.namespace, s.outConfigMapName, err)
+			klog.Errorf("Error getting %s/%s config map: %v", s.namespace, outConfigMapName, err)
 			return oldChecksum, err
 		}
 	}
 	return oldChecksum, err
 }
 
-func (s *Scorer) updateConfigMap() error {
+// ProfileAnnotation of the output ConfigMap has the name of the active profile
+const ProfileAnnotation = "cluster-autoscaler-priority-helper/profile"
+
+func profileAnnotations(profile string) map[string]string {
+	if profile == "" {
+		return nil
+	}
+	return map[string]string{ProfileAnnotation: profile}
+}
+
+// annotateProfile sets (or removes when there is no profile) the profile annotation of the output ConfigMap
+func (s *Scorer) annotateProfile(outConfigMapName, profile string) error {
+	cm, err := s.cmLister.ConfigMaps(s.namespace).Get(outConfigMapName)
+	if err != nil {
+		return err
+	}
+	current, found := cm.ObjectMeta.Annotations[ProfileAnnotation]
+	if current == profile && (found || profile == "") {
+		return nil
+	}
+	var value interface{}
+	if profile != "" {
+		value = profile
+	}
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{ProfileAnnotation: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.clientset.CoreV1().ConfigMaps(s.namespace).Patch(outConfigMapName, types.MergePatchType, patchBytes)
+	return err
+}
+
+// causes of the output ConfigMap updates
+const (
+	causeConfigMapChanged = "configmap-changed"
+	causeDataChanged      = "data-changed"
+	causeRefreshInterval  = "refresh-interval"
+	causeConfigChanged    = "config-changed"
+)
+
+func (s *Scorer) updateConfigMap(cause string) error {
 	var oldChecksum string
 	var patchBytes, yamlData []byte
 	var err error
 
-	priorities := s.computeScores()
+	outConfigMapName, breakdownConfigMapName := s.OutputConfigMaps()
+	result, err := s.computeScores()
+	if err != nil {
+		metrics.IncConfigMapUpdates(outConfigMapName, "skipped_external_scorer")
+		return err
+	}
+	priorities := result.Priorities
 	if len(priorities) == 0 {
 		// return fmt.Errorf("update config map skipped because no data yet to compute priorities")
 		klog.Warningf("update config map skipped because no data yet to compute priorities")
+		metrics.IncConfigMapUpdates(outConfigMapName, "skipped_no_data")
 		return nil
 	}
 	if yamlData, err = yaml.Marshal(priorities); err != nil {
@@ -311,19 +493,35 @@ func (s *Scorer) updateConfigMap() error {
 	default:
 	}
 
-	oldChecksum, err = s.getOrUpdateOutputConfigMapChecksum(yamlData, checksum)
+	// the breakdown can change even if the priorities are the same
+	if err := s.publishBreakdown(breakdownConfigMapName, result); err != nil {
+		klog.Errorf("Error publishing breakdown config map %s/%s: %v", s.namespace, breakdownConfigMapName, err)
+	}
+
+	oldChecksum, err = s.getOrUpdateOutputConfigMapChecksum(outConfigMapName, yamlData, checksum, result.Profile)
 	if err != nil {
+		metrics.IncConfigMapUpdates(outConfigMapName, "error")
 		return err
 	} else if oldChecksum == "" /* a new fresh created ConfigMap, nothing to do */ {
+		metrics.IncConfigMapUpdates(outConfigMapName, "created")
+		s.setLastSuccessfulUpdate()
 		return nil
 	}
 
+	// the active profile is recorded even if the priorities are the same
+	if err := s.annotateProfile(outConfigMapName, result.Profile); err != nil {
+		klog.Errorf("Error annotating config map %s/%s with the profile: %v", s.namespace, outConfigMapName, err)
+	}
+
 	klog.V(3).Infof("Update config map checking checksums %s == %s : %t", checksum, oldChecksum, oldChecksum == checksum)
 	if oldChecksum == checksum {
 		klog.V(1).Infof("Update config map skipped because of checksum (%s), last update was at %s", checksum, s.lastChange)
+		metrics.IncConfigMapUpdates(outConfigMapName, "skipped_checksum")
+		s.setLastSuccessfulUpdate()
 		return nil
 	}
 
+	oldPriorities := s.publishedPriorities()
 	if patchBytes, err = json.Marshal([]Patch{{
 		Op:   "replace",
 		Path: "/data",
@@ -335,19 +533,20 @@ func (s *Scorer) updateConfigMap() error {
 	}
 
 	if _, err := s.clientset.CoreV1().ConfigMaps(s.namespace).
-		Patch(s.outConfigMapName, types.JSONPatchType, patchBytes); err != nil {
+		Patch(outConfigMapName, types.JSONPatchType, patchBytes); err != nil {
+		metrics.IncConfigMapUpdates(outConfigMapName, "error")
 		return err
 	}
 
+	metrics.IncConfigMapUpdates(outConfigMapName, "updated")
+	s.setLastSuccessfulUpdate()
+	s.reportPrioritiesChanges(cause, oldPriorities, priorities)
 	s.lastChange = time.Now()
 	klog.V(1).Infof("Updated config map at %s", s.lastChange)
 	return nil
 }
 
-func (s *Scorer) computeScores() map[int][]string {
-	var priorities map[int]map[string]struct{}
-	var resPriorities map[int][]string
-
+func (s *Scorer) computeScores() (*Result, error) {
 	// check if some hints are avilable to use them later
 	if err := s.getOrCreateHints(); err != nil {
 		klog.Errorf("Error preparing hints: %v", err)
@@ -355,46 +554,123 @@ func (s *Scorer) computeScores() map[int][]string {
 		klog.V(5).Infof("Successfully prepared hints")
 	}
 
-	if asgNames, err := s.asgDiscoverer.GetASGNames(); err != nil {
-		klog.Errorf("Error computing scores: %v", err)
-	} else {
-		priorities = make(map[int]map[string]struct{})
-		klog.V(2).Infof("computeScores GetASGNames() => %v\n", asgNames)
-		for _, asgName := range asgNames {
-			prio, err := s.computeScoreForASG(asgName)
-			if err != nil {
-				klog.V(2).Infof("computeScoreForASG(%s) => error %v\n", asgName, err)
-				continue
-			}
-			klog.V(2).Infof("computeScoreForASG(%s) => %d\n", asgName, prio)
-
-			nameForAsg := func(asgName string) string { return asgName }
-			if s.config.IgnoreAZs {
-				nameForAsg = func(asgName string) string {
-					// this assume that the asgName is ending with -<Availability Zone>
-					// and that for spot the string "-spot-" is included in the ASG name
-					if strings.Contains(asgName, "-spot-") {
-						return asgName[0 : len(asgName)-1]
-					}
-					return asgName
-				}
-			}
+	snap := s.TakeSnapshot()
 
-			if asgs, found := priorities[prio]; found {
-				asgs[nameForAsg(asgName)] = struct{}{}
-			} else {
-				priorities[prio] = map[string]struct{}{
-					nameForAsg(asgName): struct{}{},
-				}
+	s.dataMu.RLock()
+	cfg, hints, profile := s.config, s.hints, s.profile
+	s.dataMu.RUnlock()
+
+	result := computeScores(snap, cfg, hints)
+	result.Profile = profile
+	if err := s.applyExternalScorer(snap, cfg, hints, result); err != nil {
+		return nil, err
+	}
+	updateMetrics(s.target, snap, result)
+	s.reportExpensiveSpots(result)
+
+	s.dataMu.Lock()
+	s.lastResult = result
+	s.dataMu.Unlock()
+	return result, nil
+}
+
+func updateMetrics(target string, snap *Snapshot, result *Result) {
+	metrics.ResetScores(target)
+	for _, b := range result.Breakdown {
+		metrics.SetASGScore(target, b.ASGName, b.Priority, b.Components())
+	}
+	for k, price := range snap.SpotPrices {
+		iDetails := utils.InstanceDetails{}
+		(&iDetails).FromString(k)
+		metrics.SetSpotPrice(target, iDetails.InstanceType, iDetails.AvailabilityZone, price)
+	}
+	for k, price := range snap.OnDemandPrices {
+		iDetails := utils.InstanceDetails{}
+		(&iDetails).FromString(k)
+		metrics.SetOnDemandPrice(target, iDetails.InstanceType, iDetails.AvailabilityZone, price)
+	}
+	for k, data := range snap.Advisor {
+		parts := strings.SplitN(k, "--", 2)
+		metrics.SetAdvisorProbability(target, parts[1], parts[0], data.Probability)
+	}
+	for _, b := range result.Breakdown {
+		if b.SpotPriceRatio > 0 {
+			metrics.SetSpotPriceRatio(target, b.ASGName, b.SpotPriceRatio, b.ExpensiveSpot)
+		}
+	}
+}
+
+func nameForASG(cfg config.ScorerConfiguration, asgName string) string {
+	if cfg.IgnoreAZs {
+		// this assume that the asgName is ending with -<Availability Zone>
+		// and that for spot the string "-spot-" is included in the ASG name
+		if strings.Contains(asgName, "-spot-") {
+			return asgName[0 : len(asgName)-1]
+		}
+	}
+	return asgName
+}
+
+func computeScores(snap *Snapshot, cfg config.ScorerConfiguration, hints Hints) *Result {
+	result := &Result{Time: snap.Time}
+	pools, err := cfg.ResolvePools()
+	if err != nil {
+		klog.Errorf("Invalid pools, ignoring them: %v", err)
+		pools = nil
+	}
+	asgNames := snap.GetASGNames()
+	klog.V(2).Infof("computeScores GetASGNames() => %v\n", asgNames)
+	for _, asgName := range asgNames {
+		breakdown, err := computeScoreForASG(snap, cfg, pools, asgName)
+		if err != nil {
+			klog.V(2).Infof("computeScoreForASG(%s) => error %v\n", asgName, err)
+			continue
+		}
+		klog.V(2).Infof("computeScoreForASG(%s) => %d\n", asgName, breakdown.Priority)
+		result.Breakdown = append(result.Breakdown, breakdown)
+	}
+	// the maluses relative to the other ASGs are part of the score the hints are applied to
+	applyRelativeMaluses(result, cfg, pools)
+	configs := configsByPool(cfg, pools)
+	for i := range result.Breakdown {
+		applyHints(&result.Breakdown[i], configs[result.Breakdown[i].Pool], hints)
+	}
+	result.setPriorities(hints)
+	return result
+}
+
+// configsByPool returns the scorer configuration of every pool, the one of the ASGs w/o pool is at ""
+func configsByPool(cfg config.ScorerConfiguration, pools []config.Pool) map[string]config.ScorerConfiguration {
+	configs := map[string]config.ScorerConfiguration{"": cfg}
+	for _, pool := range pools {
+		configs[pool.Name] = pool.Config
+	}
+	return configs
+}
+
+// setPriorities groups the ASGs of the breakdown by priority, the vetoed ones are left out,
+// and merges the hinted priorities
+func (result *Result) setPriorities(hints Hints) {
+	priorities := make(map[int]map[string]struct{})
+	for _, breakdown := range result.Breakdown {
+		if breakdown.Vetoed {
+			continue
+		}
+		prio := breakdown.Priority
+		if asgs, found := priorities[prio]; found {
+			asgs[breakdown.Name] = struct{}{}
+		} else {
+			priorities[prio] = map[string]struct{}{
+				breakdown.Name: struct{}{},
 			}
 		}
 	}
 
-	resPriorities = make(map[int][]string)
+	resPriorities := make(map[int][]string)
 
 	for prio, asgs := range priorities {
 		asgNames := []string{}
-		for asg, _ := range asgs {
+		for asg := range asgs {
 			asgNames = append(asgNames, asg)
 		}
 		sort.Strings(asgNames)
@@ -403,29 +679,35 @@ func (s *Scorer) computeScores() map[int][]string {
 
 	// merge priorities with hinted ones
 	klog.V(5).Infof("Priorities before hints: %v", resPriorities)
-	klog.V(5).Infof("Hints for priorities: %v", s.hints.priorities)
-	for prio, hinted := range s.hints.priorities {
-		if asgs, found := resPriorities[prio]; found {
-			for _, hint := range hinted {
-				asgs = append(asgs, hint)
-			}
-		} else {
-			resPriorities[prio] = hinted
-		}
+	klog.V(5).Infof("Hints for priorities: %v", hints.priorities)
+	for prio, hinted := range hints.priorities {
+		resPriorities[prio] = append(resPriorities[prio], hinted...)
 	}
 
 	klog.V(5).Infof("Priorities after hints: %v", resPriorities)
-	return resPriorities
+	result.Priorities = resPriorities
 }
 
-func (s *Scorer) computeScoreForASG(asgName string) (int, error) {
+// computeScoreForASG returns the breakdown of the built-in score of an ASG, the maluses relative
+// to the other ASGs and the hints are applied afterwards by computeScores.
+func computeScoreForASG(snap *Snapshot, cfg config.ScorerConfiguration, pools []config.Pool, asgName string) (ScoreBreakdown, error) {
 	var iDetails utils.InstanceDetails
-	prio := s.config.BasePriority
+	// the ASGs of a pool are scored with the configuration of the pool
+	pool := config.PoolFor(pools, asgName, snap.ASGs[asgName].Tags)
+	if pool != nil {
+		cfg = pool.Config
+	}
+	nodeCounts := snap.nodesFor(pool)
+	prio := cfg.BasePriority
+	breakdown := ScoreBreakdown{ASGName: asgName, Name: nameForASG(cfg, asgName), Base: prio, Derived: cfg.Derived()}
+	if pool != nil {
+		breakdown.Pool = pool.Name
+	}
 	klog.V(3).Infof("Scorer compute priority for %s\t initial prio=%d", asgName, prio)
-	rDetails, err := s.asgDiscoverer.GetDetailsFor(asgName)
+	rDetails, err := snap.GetDetailsFor(asgName)
 	if err != nil {
 		klog.Errorf(err.Error())
-		return -1, err
+		return breakdown, err
 	}
 	if rDetails.IsMixedInstanceTypes() {
 		mDetails := rDetails.(utils.MixedInstanceTypesDetails)
@@ -453,7 +735,7 @@ func (s *Scorer) computeScoreForASG(asgName string) (int, error) {
 		//
 		highest := float64(0.0)
 		for _, it := range mDetails.InstanceTypes {
-			if itPrice, found := s.pricer.GetPriceFor(it, iDetails.AvailabilityZone, iDetails.IsSpot); found && highest <= itPrice {
+			if itPrice, found := snap.GetPriceFor(it, iDetails.AvailabilityZone, iDetails.IsSpot); found && highest <= itPrice {
 				highest = itPrice
 				iDetails.InstanceType = it
 			}
@@ -461,46 +743,96 @@ func (s *Scorer) computeScoreForASG(asgName string) (int, error) {
 	} else {
 		iDetails = rDetails.(utils.InstanceDetails)
 	}
+	breakdown.InstanceType = iDetails.InstanceType
+	breakdown.InstanceTypes = rDetails.GetInstanceTypes()
+	breakdown.AvailabilityZone = iDetails.AvailabilityZone
+	breakdown.IsSpot = iDetails.IsSpot
 
 	if iDetails.IsSpot {
-		prio += s.config.BonusForSpot
-		klog.V(3).Infof("Scorer compute priority for %s\t prio+=%d because is spot (prio=%d)", asgName, s.config.BonusForSpot, prio)
-	} else {
-		prio -= s.config.MalusForOnDemand
-		klog.V(3).Infof("Scorer compute priority for %s\t prio-=%d because is ondemand (prio=%d)", asgName, s.config.MalusForOnDemand, prio)
+		// a spot price near or above the on-demand one is an anomaly, the spot ASG doesn't deserve the bonuses
+		if ratio, found := spotPriceRatio(snap, iDetails); found {
+			breakdown.SpotPriceRatio = ratio
+			breakdown.ExpensiveSpot = cfg.SpotPriceRatioThreshold > 0 && ratio > cfg.SpotPriceRatioThreshold
+		}
+	}
+	if breakdown.ExpensiveSpot {
+		klog.V(2).Infof("Scorer compute priority for %s\t spot price is %.2f times the on-demand one, no spot bonus", asgName, breakdown.SpotPriceRatio)
+		if cfg.IsEnabled("expensive_spot_malus") {
+			prio -= cfg.MalusForExpensiveSpot
+			breakdown.ExpensiveSpotMalus = cfg.MalusForExpensiveSpot
+			klog.V(3).Infof("Scorer compute priority for %s\t prio-=%d because spot is expensive (prio=%d)", asgName, cfg.MalusForExpensiveSpot, prio)
+		}
+	} else if iDetails.IsSpot {
+		if cfg.IsEnabled("spot_bonus") {
+			prio += cfg.BonusForSpot
+			breakdown.SpotBonus = cfg.BonusForSpot
+			klog.V(3).Infof("Scorer compute priority for %s\t prio+=%d because is spot (prio=%d)", asgName, cfg.BonusForSpot, prio)
+		}
+	} else if cfg.IsEnabled("ondemand_malus") {
+		prio -= cfg.MalusForOnDemand
+		breakdown.OnDemandMalus = cfg.MalusForOnDemand
+		klog.V(3).Infof("Scorer compute priority for %s\t prio-=%d because is ondemand (prio=%d)", asgName, cfg.MalusForOnDemand, prio)
 	}
 
 	if iDetails.IsSpot {
 		instanceTypes := rDetails.GetInstanceTypes()
 		count := 0
-		totProb := 0
 
 		countNodes := func(it string, id utils.InstanceDetails) int {
-			return s.nodesDistribution.GetCountFor(it, id.AvailabilityZone, "spot")
+			return nodeCounts.GetCountFor(it, id.AvailabilityZone, "spot")
 		}
-		if s.config.IgnoreAZs {
+		if cfg.IgnoreAZs {
 			countNodes = func(it string, _ utils.InstanceDetails) int {
-				return s.nodesDistribution.GetCountForInstanceType(it, "spot")
+				return nodeCounts.GetCountForInstanceType(it, "spot")
 			}
 		}
 		for _, it := range instanceTypes {
-			// count += s.nodesDistribution.GetCountFor(it, iDetails.AvailabilityZone, "spot")
 			count += countNodes(it, iDetails)
-			totProb += s.spotAdvisor.GetProbabilityFor(iDetails.GetRegion(), "Linux", it)
 		}
-		avgProb := float64(totProb) / float64(len(instanceTypes))
+		avgProb := snap.GetAverageProbabilityFor(iDetails.GetRegion(), instanceTypes)
+		breakdown.Probability = avgProb
+		breakdown.NodeCount = count
+
+		if cfg.IsEnabled("probability_malus") && cfg.ScoringModel != config.ScoringModelExpectedCost {
+			breakdown.ProbabilityMalus = int(math.Round(avgProb * float64(cfg.MalusForProbability)))
+			prio -= breakdown.ProbabilityMalus
+			klog.V(3).Infof("Scorer compute priority for %s\t (probability on average is %.2f) prio-=%.2f*%d (prio=%d) %v",
+				asgName, avgProb, avgProb, cfg.MalusForProbability, prio, instanceTypes)
+		}
 
-		prio -= int(math.Round(avgProb * float64(s.config.MalusForProbability)))
-		klog.V(3).Infof("Scorer compute priority for %s\t (probability on average is %d) prio-=%d*%d (prio=%d) %v",
-			asgName, avgProb, avgProb, s.config.MalusForProbability, prio, instanceTypes)
+		// the savings of the instance types the spot advisor knows, on average
+		totSavings, known := 0, 0
+		for _, it := range instanceTypes {
+			if saving := snap.GetSavingFor(iDetails.GetRegion(), it); saving >= 0 {
+				totSavings += saving
+				known++
+			}
+		}
+		breakdown.Savings = -1
+		if known > 0 {
+			breakdown.Savings = float64(totSavings) / float64(known)
+			if cfg.IsEnabled("savings_bonus") && !breakdown.ExpensiveSpot {
+				breakdown.SavingsBonus = int(math.Round(breakdown.Savings / 100 * float64(cfg.BonusForSavings)))
+				prio += breakdown.SavingsBonus
+				klog.V(3).Infof("Scorer compute priority for %s\t (savings on average are %.0f%%) prio+=%d (prio=%d) %v",
+					asgName, breakdown.Savings, breakdown.SavingsBonus, prio, instanceTypes)
+			}
+		}
 
-		prio -= (count * s.config.MalusForNodeDistribution)
-		klog.V(3).Infof("Scorer compute priority for %s\t (node distribution, same type in same zone) prio-=%d*%d (prio=%d) %v",
-			asgName, count, s.config.MalusForNodeDistribution, prio, instanceTypes)
+		if cfg.IsEnabled("node_distribution_malus") {
+			breakdown.NodeDistributionMalus = count * cfg.MalusForNodeDistribution
+			prio -= breakdown.NodeDistributionMalus
+			klog.V(3).Infof("Scorer compute priority for %s\t (node distribution, same type in same zone) prio-=%d*%d (prio=%d) %v",
+				asgName, count, cfg.MalusForNodeDistribution, prio, instanceTypes)
+		}
 	} else {
-		count := s.nodesDistribution.GetCountForAZ(iDetails.AvailabilityZone)
-		prio -= (count * s.config.MalusForNodeDistributionAZOnly)
-		klog.V(3).Infof("Scorer compute priority for %s\t (node distribution, same zone) prio-=%d*%d (prio=%d)", asgName, count, s.config.MalusForNodeDistributionAZOnly, prio)
+		count := nodeCounts.GetCountForAZ(iDetails.AvailabilityZone)
+		breakdown.NodeCount = count
+		if cfg.IsEnabled("node_distribution_malus") {
+			breakdown.NodeDistributionMalus = count * cfg.MalusForNodeDistributionAZOnly
+			prio -= breakdown.NodeDistributionMalus
+			klog.V(3).Infof("Scorer compute priority for %s\t (node distribution, same zone) prio-=%d*%d (prio=%d)", asgName, count, cfg.MalusForNodeDistributionAZOnly, prio)
+		}
 	}
 
 	// prefer smaller instances
@@ -510,28 +842,95 @@ func (s *Scorer) computeScoreForASG(asgName string) (int, error) {
 	// 	prio -= ((cores * 2) + ramgb)
 	// }
 
-	if !s.config.IgnoreAZs || !iDetails.IsSpot {
-		if price, found := s.pricer.GetPriceFor(iDetails.InstanceType, iDetails.AvailabilityZone, iDetails.IsSpot); found {
-			prio -= int(price * float64(s.config.MalusForPrice))
-			klog.V(3).Infof("Scorer compute priority for %s\t (price) prio-=int(%f*%d) (prio=%d)", asgName, price, s.config.MalusForPrice, prio)
+	if price, found := snap.GetPriceFor(iDetails.InstanceType, iDetails.AvailabilityZone, iDetails.IsSpot); found {
+		breakdown.Price = price
+		breakdown.HasPrice = true
+	}
+	// the prices are compared per unit of capacity when they are normalized
+	capacity, unit, hasCapacity := capacityFor(snap, cfg, iDetails.GetRegion(), iDetails.InstanceType)
+	if cores := snap.GetCoresFor(iDetails.GetRegion(), iDetails.InstanceType); cores > 0 {
+		breakdown.VCPUs = cores
+	}
+	if breakdown.HasPrice && !hasCapacity {
+		// the hourly price is usually higher than the normalized ones, the ASG is not favored for its unknown capacity
+		klog.Warningf("no capacity information for %s (%s), the hourly price is compared instead of the price per %s",
+			asgName, iDetails.InstanceType, unit)
+		capacity, unit = 1, "hour"
+		breakdown.PriceNotNormalized = true
+	}
+	if breakdown.HasPrice {
+		breakdown.ComparedPrice = breakdown.Price / capacity
+		breakdown.PriceUnit = unit
+	}
+	hasComparedPrice := breakdown.HasPrice
+	if cfg.ScoringModel == config.ScoringModelExpectedCost {
+		// the malus depends on the expected cost of the other ASGs, see applyRelativeMaluses
+		if hasComparedPrice && (!cfg.IgnoreAZs || !iDetails.IsSpot) && cfg.IsEnabled("expected_cost_malus") {
+			breakdown.ExpectedCost = expectedCost(cfg, breakdown) / capacity
+			breakdown.HasExpectedCost = true
+		}
+	} else if (!cfg.IgnoreAZs || !iDetails.IsSpot) && cfg.IsEnabled("price_malus") {
+		if hasComparedPrice && cfg.PriceMode != "" && cfg.PriceMode != config.PriceModeAbsolute {
+			// the malus depends on the price of the other ASGs, see applyRelativeMaluses
+			breakdown.HasRelativePrice = true
+		} else if hasComparedPrice {
+			breakdown.PriceMalus = int(breakdown.ComparedPrice * float64(cfg.MalusForPrice))
+			prio -= breakdown.PriceMalus
+			klog.V(3).Infof("Scorer compute priority for %s\t (price per %s) prio-=int(%f*%d) (prio=%d)",
+				asgName, unit, breakdown.ComparedPrice, cfg.MalusForPrice, prio)
 		} else {
 			klog.Warningf("no price information for %s", asgName)
 		}
 	}
 
+	if cfg.Expression != "" && cfg.IsEnabled("expression") {
+		value, err := evaluateExpression(snap, cfg, breakdown, iDetails, nodeCounts, prio)
+		if err != nil {
+			klog.Errorf("Score expression for %s: %v", asgName, err)
+		} else if cfg.ExpressionMode == config.ExpressionModeReplace {
+			// the built-in components are dropped, the expression is the whole score before the hints
+			breakdown.Base, breakdown.SpotBonus, breakdown.SavingsBonus, breakdown.OnDemandMalus = 0, 0, 0, 0
+			breakdown.ExpensiveSpotMalus = 0
+			breakdown.ProbabilityMalus, breakdown.NodeDistributionMalus, breakdown.PriceMalus = 0, 0, 0
+			breakdown.HasRelativePrice, breakdown.HasExpectedCost = false, false
+			breakdown.Expression = value
+			prio = value
+			klog.V(3).Infof("Scorer compute priority for %s\t (expression) prio=%d", asgName, prio)
+		} else {
+			breakdown.Expression = value
+			prio += value
+			klog.V(3).Infof("Scorer compute priority for %s\t (expression) prio+=%d (prio=%d)", asgName, value, prio)
+		}
+	}
+
+	breakdown.Priority = prio
+	return breakdown, nil
+}
+
+// applyHints adds the hinted bonus and malus to the score of an ASG, the priority can't go below zero
+func applyHints(breakdown *ScoreBreakdown, cfg config.ScorerConfiguration, hints Hints) {
+	asgName, prio := breakdown.ASGName, breakdown.Priority
 	// check for hinted bonus/malus
-	for value, regexps := range s.hints.bonus {
+	if !cfg.IsEnabled("hints_bonus") {
+		hints.bonus = nil
+	}
+	if !cfg.IsEnabled("hints_malus") {
+		hints.malus = nil
+	}
+	for value, regexps := range hints.bonus {
 		for _, re := range regexps {
 			if re.FindStringIndex(asgName) != nil {
 				prio += value
+				breakdown.HintsBonus += value
 				klog.V(3).Infof("Scorer compute priority for %s\t (bonus hints) prio+=%d (prio=%d)", asgName, value, prio)
 			}
 		}
 	}
-	for value, regexps := range s.hints.malus {
+	for value, regexps := range hints.malus {
 		for _, re := range regexps {
 			if re.FindStringIndex(asgName) != nil {
 				prio -= value
+				breakdown.HintsMalus += value
 				klog.V(3).Infof("Scorer compute priority for %s\t (malus hints) prio-=%d (prio=%d)", asgName, value, prio)
 			}
 		}
@@ -539,7 +938,7 @@ func (s *Scorer) computeScoreForASG(asgName string) (int, error) {
 
 	if prio < 0 {
 		klog.V(3).Infof("Scorer compute priority for %s\t (prio=%d) return zero as lowest priority", asgName, prio)
-		return 0, nil
+		prio = 0
 	}
-	return prio, nil
+	breakdown.Priority = prio
 }
@@ -0,0 +1,112 @@
+package scorer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/nodes"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+)
+
+func TestSavingsBonus(t *testing.T) {
+	tests := []struct {
+		name          string
+		instanceTypes []string
+		spotPrice     float64
+		disabled      []string
+		savings       float64
+		bonus         int
+	}{
+		{name: "proportional", instanceTypes: []string{"m5.large"}, spotPrice: 0.03, savings: 70, bonus: 140},
+		{name: "no savings", instanceTypes: []string{"t3.large"}, spotPrice: 0.03, savings: 0, bonus: 0},
+		{name: "unknown savings", instanceTypes: []string{"x9.large"}, spotPrice: 0.03, savings: -1, bonus: 0},
+		{name: "average of the known savings", instanceTypes: []string{"m5.large", "c5.large", "x9.large"}, spotPrice: 0.03, savings: 50, bonus: 100},
+		{name: "expensive spot", instanceTypes: []string{"m5.large"}, spotPrice: 0.2, savings: 70, bonus: 0},
+		{name: "disabled", instanceTypes: []string{"m5.large"}, spotPrice: 0.03, disabled: []string{"savings_bonus"}, savings: 70, bonus: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap := NewSnapshot(time.Now())
+			snap.ASGs["app-spot-eu-west-1a"] = ASGSnapshot{
+				InstanceTypes: tt.instanceTypes, AvailabilityZone: "eu-west-1a", IsSpot: true, IsMixed: len(tt.instanceTypes) > 1,
+			}
+			for _, it := range tt.instanceTypes {
+				snap.SpotPrices[priceKey(it, "eu-west-1a", true)] = tt.spotPrice
+				snap.OnDemandPrices[priceKey(it, "eu-west-1a", false)] = 0.1
+			}
+			snap.Advisor[advisorKey("eu-west-1", "m5.large")] = AdvisorData{Saving: 70}
+			snap.Advisor[advisorKey("eu-west-1", "c5.large")] = AdvisorData{Saving: 30}
+			snap.Advisor[advisorKey("eu-west-1", "t3.large")] = AdvisorData{Saving: 0}
+			cfg := config.ScorerConfiguration{
+				BasePriority: 1000, BonusForSavings: 200, SpotPriceRatioThreshold: 1, DisabledComponents: tt.disabled,
+			}
+			b := computeScores(snap, cfg, Hints{}).Breakdown[0]
+			if b.Savings != tt.savings || b.SavingsBonus != tt.bonus {
+				t.Errorf("savings %.0f, bonus %d, want %.0f, %d", b.Savings, b.SavingsBonus, tt.savings, tt.bonus)
+			}
+		})
+	}
+}
+
+// newPoolsSnapshot returns a snapshot with a batch and a web spot ASG of the same instance type
+// and AZ, the batch pool has its own nodes distribution.
+func newPoolsSnapshot() *Snapshot {
+	snap := NewSnapshot(time.Now())
+	for _, name := range []string{"batch-spot-eu-west-1a", "web-spot-eu-west-1a"} {
+		snap.ASGs[name] = ASGSnapshot{InstanceTypes: []string{"m5.large"}, AvailabilityZone: "eu-west-1a", IsSpot: true}
+	}
+	snap.Nodes[priceKey("m5.large", "eu-west-1a", true)] = 5
+	snap.PoolNodes = map[string]nodes.Counts{
+		"batch": {priceKey("m5.large", "eu-west-1a", true): 2},
+		"other": {priceKey("m5.large", "eu-west-1a", true): 1},
+	}
+	return snap
+}
+
+func poolsConfig(t *testing.T) (config.ScorerConfiguration, []config.Pool) {
+	cfg := config.ScorerConfiguration{
+		BasePriority: 1000, MalusForNodeDistribution: 10, HintsConfigMapName: "hints",
+		Pools: []config.PoolConfiguration{
+			{
+				Name: "batch", NamePattern: "^batch-", NodeSelector: "workload=batch",
+				Scorer: json.RawMessage(`{"malusForNodeDistribution": 1}`),
+			},
+			{Name: "other", NamePattern: "^other-", NodeSelector: "workload=other"},
+		},
+	}
+	pools, err := cfg.ResolvePools()
+	if err != nil {
+		t.Fatalf("ResolvePools() error = %v", err)
+	}
+	return cfg, pools
+}
+
+func TestPools(t *testing.T) {
+	cfg, _ := poolsConfig(t)
+	tests := []struct {
+		asgName   string
+		pool      string
+		nodeCount int
+		malus     int
+	}{
+		{asgName: "batch-spot-eu-west-1a", pool: "batch", nodeCount: 2, malus: 2},
+		{asgName: "web-spot-eu-west-1a", nodeCount: 5, malus: 50},
+	}
+	result := computeScores(newPoolsSnapshot(), cfg, Hints{})
+	for _, tt := range tests {
+		t.Run(tt.asgName, func(t *testing.T) {
+			for _, b := range result.Breakdown {
+				if b.ASGName != tt.asgName {
+					continue
+				}
+				if b.Pool != tt.pool || b.NodeCount != tt.nodeCount || b.NodeDistributionMalus != tt.malus {
+					t.Errorf("pool %q, node count %d, node distribution malus %d, want %q, %d, %d",
+						b.Pool, b.NodeCount, b.NodeDistributionMalus, tt.pool, tt.nodeCount, tt.malus)
+				}
+				return
+			}
+			t.Errorf("no breakdown for %s", tt.asgName)
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package scorer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/aws"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/nodes"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/spotadvisor"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/utils"
+)
+
+const advisorOSType = "Linux"
+
+// ASGSnapshot keeps the details of an ASG as they were discovered
+type ASGSnapshot struct {
+	InstanceTypes    []string `json:"instanceTypes"`
+	AvailabilityZone string   `json:"availabilityZone"`
+	IsSpot           bool     `json:"isSpot"`
+	IsMixed          bool     `json:"isMixed"`
+	// Tags are used to classify the ASG in a pool
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+func (a ASGSnapshot) details() utils.DetailsResult {
+	iDetails := utils.InstanceDetails{AvailabilityZone: a.AvailabilityZone, IsSpot: a.IsSpot}
+	if a.IsMixed {
+		return utils.MixedInstanceTypesDetails{InstanceDetails: iDetails, InstanceTypes: a.InstanceTypes}
+	}
+	if len(a.InstanceTypes) > 0 {
+		iDetails.InstanceType = a.InstanceTypes[0]
+	}
+	return iDetails
+}
+
+// AdvisorData is the spot advisor data for an instance type in a region
+type AdvisorData struct {
+	Probability int `json:"r"`
+	Saving      int `json:"s"`
+	// Cores and Memory (GiB) of the instance type, 0 if unknown
+	Cores  int     `json:"cores,omitempty"`
+	Memory float64 `json:"memory,omitempty"`
+}
+
+// Snapshot is a consistent copy of all the data used to compute the scores,
+// it is taken from the data sources before every computation and it can be
+// altered (what-if) or stored and replayed (backtest) w/o touching the sources.
+type Snapshot struct {
+	Time           time.Time              `json:"time"`
+	ASGs           map[string]ASGSnapshot `json:"asgs"`
+	SpotPrices     map[string]float64     `json:"spotPrices"`
+	OnDemandPrices map[string]float64     `json:"onDemandPrices"`
+	Advisor        map[string]AdvisorData `json:"advisor"`
+	Nodes          nodes.Counts           `json:"nodes"`
+	// PoolNodes are the distributions of the nodes of the pools with a node selector
+	PoolNodes map[string]nodes.Counts `json:"poolNodes,omitempty"`
+}
+
+func NewSnapshot(t time.Time) *Snapshot {
+	return &Snapshot{
+		Time:           t,
+		ASGs:           make(map[string]ASGSnapshot),
+		SpotPrices:     make(map[string]float64),
+		OnDemandPrices: make(map[string]float64),
+		Advisor:        make(map[string]AdvisorData),
+		Nodes:          make(nodes.Counts),
+	}
+}
+
+func priceKey(instanceType, az string, isSpot bool) string {
+	return (utils.InstanceDetails{InstanceType: instanceType, AvailabilityZone: az, IsSpot: isSpot}).String()
+}
+
+func advisorKey(region, instanceType string) string {
+	return fmt.Sprintf("%s--%s", region, instanceType)
+}
+
+// TakeSnapshot copies from the data sources what is needed to compute the scores
+// of the discovered ASGs, only the instance types and AZs used by them are kept.
+func TakeSnapshot(
+	asgDiscoverer *aws.ASGDiscoverer,
+	pricer *aws.Pricer,
+	spotAdvisor *spotadvisor.SpotAdvisor,
+	nodesDistribution *nodes.NodesDistribution,
+) *Snapshot {
+	snap := NewSnapshot(time.Now())
+	asgNames, _ := asgDiscoverer.GetASGNames()
+	for _, asgName := range asgNames {
+		rDetails, err := asgDiscoverer.GetDetailsFor(asgName)
+		if err != nil {
+			continue
+		}
+		var iDetails utils.InstanceDetails
+		if rDetails.IsMixedInstanceTypes() {
+			iDetails = rDetails.(utils.MixedInstanceTypesDetails).InstanceDetails
+		} else {
+			iDetails = rDetails.(utils.InstanceDetails)
+		}
+		asgSnap := ASGSnapshot{
+			InstanceTypes:    rDetails.GetInstanceTypes(),
+			AvailabilityZone: iDetails.AvailabilityZone,
+			IsSpot:           iDetails.IsSpot,
+			IsMixed:          rDetails.IsMixedInstanceTypes(),
+			Tags:             asgDiscoverer.GetTagsFor(asgName),
+		}
+		snap.ASGs[asgName] = asgSnap
+
+		for _, it := range asgSnap.InstanceTypes {
+			if asgSnap.AvailabilityZone != "" {
+				if price, found := pricer.GetPriceFor(it, asgSnap.AvailabilityZone, true); found {
+					snap.SpotPrices[priceKey(it, asgSnap.AvailabilityZone, true)] = price
+				}
+				if price, found := pricer.GetPriceFor(it, asgSnap.AvailabilityZone, false); found {
+					snap.OnDemandPrices[priceKey(it, asgSnap.AvailabilityZone, false)] = price
+				}
+			}
+			region := rDetails.GetRegion()
+			snap.Advisor[advisorKey(region, it)] = AdvisorData{
+				Probability: spotAdvisor.GetProbabilityFor(region, advisorOSType, it),
+				Saving:      spotAdvisor.GetSavingFor(region, advisorOSType, it),
+				Cores:       spotAdvisor.GetCoresFor(it),
+				Memory:      spotAdvisor.GetMemoryFor(it),
+			}
+		}
+	}
+	snap.Nodes = nodes.Counts(nodesDistribution.GetData())
+	return snap
+}
+
+// Copy returns a deep copy of the snapshot, that can be altered safely
+func (s *Snapshot) Copy() *Snapshot {
+	res := NewSnapshot(s.Time)
+	for k, v := range s.ASGs {
+		v.InstanceTypes = append([]string{}, v.InstanceTypes...)
+		if v.Tags != nil {
+			tags := make(map[string]string, len(v.Tags))
+			for tk, tv := range v.Tags {
+				tags[tk] = tv
+			}
+			v.Tags = tags
+		}
+		res.ASGs[k] = v
+	}
+	for k, v := range s.SpotPrices {
+		res.SpotPrices[k] = v
+	}
+	for k, v := range s.OnDemandPrices {
+		res.OnDemandPrices[k] = v
+	}
+	for k, v := range s.Advisor {
+		res.Advisor[k] = v
+	}
+	res.Nodes = s.Nodes.Copy()
+	if s.PoolNodes != nil {
+		res.PoolNodes = make(map[string]nodes.Counts, len(s.PoolNodes))
+		for pool, counts := range s.PoolNodes {
+			res.PoolNodes[pool] = counts.Copy()
+		}
+	}
+	return res
+}
+
+func (s *Snapshot) GetASGNames() []string {
+	asgNames := make([]string, 0, len(s.ASGs))
+	for asgName := range s.ASGs {
+		asgNames = append(asgNames, asgName)
+	}
+	sort.Strings(asgNames)
+	return asgNames
+}
+
+func (s *Snapshot) GetDetailsFor(asgName string) (utils.DetailsResult, error) {
+	if asgSnap, ok := s.ASGs[asgName]; ok {
+		return asgSnap.details(), nil
+	}
+	return utils.InstanceDetails{}, fmt.Errorf("No details found for %s", asgName)
+}
+
+func (s *Snapshot) GetPriceFor(instanceType, az string, isSpot bool) (float64, bool) {
+	if isSpot {
+		price, found := s.SpotPrices[priceKey(instanceType, az, true)]
+		return price, found
+	}
+	price, found := s.OnDemandPrices[priceKey(instanceType, az, false)]
+	return price, found
+}
+
+func (s *Snapshot) GetProbabilityFor(region, instanceType string) int {
+	if data, ok := s.Advisor[advisorKey(region, instanceType)]; ok {
+		return data.Probability
+	}
+	return -1
+}
+
+// GetAverageProbabilityFor returns the average spot advisor range of the instance types the spot
+// advisor knows, the worst range when it knows none of them.
+func (s *Snapshot) GetAverageProbabilityFor(region string, instanceTypes []string) float64 {
+	total, known := 0, 0
+	for _, it := range instanceTypes {
+		if r := s.GetProbabilityFor(region, it); r >= 0 {
+			total += r
+			known++
+		}
+	}
+	if known == 0 {
+		return worstProbability
+	}
+	return float64(total) / float64(known)
+}
+
+func (s *Snapshot) GetSavingFor(region, instanceType string) int {
+	if data, ok := s.Advisor[advisorKey(region, instanceType)]; ok {
+		return data.Saving
+	}
+	return -1
+}
+
+// nodesFor returns the nodes distribution to use for the pool, the global one when
+// the pool has no node selector (or the snapshot has no data for it).
+func (s *Snapshot) nodesFor(pool *config.Pool) nodes.Counts {
+	if pool != nil {
+		if counts, ok := s.PoolNodes[pool.Name]; ok {
+			return counts
+		}
+	}
+	return s.Nodes
+}
+
+// GetCoresFor returns the vCPUs of the instance type, -1 if unknown
+func (s *Snapshot) GetCoresFor(region, instanceType string) int {
+	if data, ok := s.Advisor[advisorKey(region, instanceType)]; ok && data.Cores > 0 {
+		return data.Cores
+	}
+	return -1
+}
+
+// GetMemoryFor returns the memory in GiB of the instance type, -1 if unknown
+func (s *Snapshot) GetMemoryFor(region, instanceType string) float64 {
+	if data, ok := s.Advisor[advisorKey(region, instanceType)]; ok && data.Memory > 0 {
+		return data.Memory
+	}
+	return -1
+}
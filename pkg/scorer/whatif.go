@@ -0,0 +1,392 @@
+package scorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/klog"
+
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/extscorer"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/nodes"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/scorer/config"
+	"github.com/safanaj/cluster-autoscaler-priority-helper/pkg/utils"
+)
+
+// ErrNoData is returned when there is not yet any discovered ASG to score
+var ErrNoData = fmt.Errorf("no data yet to compute priorities")
+
+// ExternalScorerError is returned by WhatIf when the external scorer fails and its failure policy is closed
+type ExternalScorerError struct {
+	Err error
+}
+
+func (e *ExternalScorerError) Error() string {
+	return fmt.Sprintf("external scorer failed: %v", e.Err)
+}
+
+// maxWhatIfRequestSize is the limit of the what-if request body
+const maxWhatIfRequestSize = 1 << 20
+
+// HintsSpec is the not-compiled form of the hints, like in the hints ConfigMap
+type HintsSpec struct {
+	Bonus      map[int][]string `json:"bonus,omitempty"`
+	Malus      map[int][]string `json:"malus,omitempty"`
+	Priorities map[int][]string `json:"priorities,omitempty"`
+}
+
+func compileHintsRules(rules map[int][]string) (map[int][]*regexp.Regexp, error) {
+	res := make(map[int][]*regexp.Regexp)
+	for prio, reList := range rules {
+		for _, re := range reList {
+			compiled, err := regexp.Compile(re)
+			if err != nil {
+				return nil, fmt.Errorf("Can't compile regexp rule for priority %d and rule %s: %v", prio, re, err)
+			}
+			res[prio] = append(res[prio], compiled)
+		}
+	}
+	return res, nil
+}
+
+func (h HintsSpec) toHints() (Hints, error) {
+	var err error
+	hints := Hints{priorities: h.Priorities}
+	if hints.bonus, err = compileHintsRules(h.Bonus); err != nil {
+		return hints, err
+	}
+	if hints.malus, err = compileHintsRules(h.Malus); err != nil {
+		return hints, err
+	}
+	return hints, nil
+}
+
+func mergeHintsRules(a, b map[int][]*regexp.Regexp) map[int][]*regexp.Regexp {
+	res := make(map[int][]*regexp.Regexp)
+	for _, m := range []map[int][]*regexp.Regexp{a, b} {
+		for prio, rules := range m {
+			res[prio] = append(res[prio], rules...)
+		}
+	}
+	return res
+}
+
+func mergeHints(a, b Hints) Hints {
+	res := Hints{
+		bonus:      mergeHintsRules(a.bonus, b.bonus),
+		malus:      mergeHintsRules(a.malus, b.malus),
+		priorities: make(map[int][]string),
+	}
+	for _, m := range []map[int][]string{a.priorities, b.priorities} {
+		for prio, asgs := range m {
+			res.priorities[prio] = append(res.priorities[prio], asgs...)
+		}
+	}
+	return res
+}
+
+// PriceOverride alters the prices matching the instance type and AZ glob patterns,
+// an empty AvailabilityZone matches all the zones.
+// Price replaces the price when not zero, then Factor multiplies it when not zero.
+type PriceOverride struct {
+	InstanceType     string  `json:"instanceType"`
+	AvailabilityZone string  `json:"availabilityZone,omitempty"`
+	Price            float64 `json:"price,omitempty"`
+	Factor           float64 `json:"factor,omitempty"`
+}
+
+// ProbabilityOverride replaces the spot advisor probability index (0-4)
+// for the instance types and regions matching the glob patterns.
+type ProbabilityOverride struct {
+	InstanceType string `json:"instanceType"`
+	Region       string `json:"region,omitempty"`
+	Probability  int    `json:"probability"`
+}
+
+// NodesOverride adds (or removes if negative) nodes to the nodes distribution, the nodes are
+// spread over the instance types and AZs of the discovered ASGs matching the patterns,
+// e.g. 20 more c5 spot nodes in any zone. They are counted in the nodes distribution
+// of the pools of those ASGs too.
+type NodesOverride struct {
+	InstanceType     string `json:"instanceType"`
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+	IsSpot           bool   `json:"isSpot"`
+	Count            int    `json:"count"`
+}
+
+// Overrides are hypothetical inputs applied to a snapshot
+type Overrides struct {
+	SpotPrices     []PriceOverride       `json:"spotPrices,omitempty"`
+	OnDemandPrices []PriceOverride       `json:"onDemandPrices,omitempty"`
+	Probabilities  []ProbabilityOverride `json:"probabilities,omitempty"`
+	Nodes          []NodesOverride       `json:"nodes,omitempty"`
+}
+
+func globMatch(pattern, value string) (bool, error) {
+	if pattern == "" {
+		return true, nil
+	}
+	return path.Match(pattern, value)
+}
+
+// instanceTypeMatch matches an instance type with a glob pattern or, when the pattern
+// has no dot, with an instance family (c5 matches c5.large but not c5n.large).
+func instanceTypeMatch(pattern, instanceType string) (bool, error) {
+	if pattern != "" && !strings.Contains(pattern, ".") {
+		return globMatch(pattern, strings.SplitN(instanceType, ".", 2)[0])
+	}
+	return globMatch(pattern, instanceType)
+}
+
+func applyPriceOverrides(prices map[string]float64, overrides []PriceOverride) error {
+	for _, o := range overrides {
+		if o.Price < 0 || o.Factor < 0 {
+			return fmt.Errorf("price override for %s can't be negative", o.InstanceType)
+		}
+		matched := false
+		for k, price := range prices {
+			iDetails := utils.InstanceDetails{}
+			(&iDetails).FromString(k)
+			itMatch, err := instanceTypeMatch(o.InstanceType, iDetails.InstanceType)
+			if err != nil {
+				return err
+			}
+			azMatch, err := globMatch(o.AvailabilityZone, iDetails.AvailabilityZone)
+			if err != nil {
+				return err
+			}
+			if !itMatch || !azMatch {
+				continue
+			}
+			matched = true
+			if o.Price != 0 {
+				price = o.Price
+			}
+			if o.Factor != 0 {
+				price *= o.Factor
+			}
+			prices[k] = price
+		}
+		if !matched {
+			return fmt.Errorf("price override %s in %q matches no known price", o.InstanceType, o.AvailabilityZone)
+		}
+	}
+	return nil
+}
+
+// Apply alters the snapshot with the hypothetical inputs, the pools are needed to know
+// the nodes distributions the nodes overrides go to.
+func (o Overrides) Apply(snap *Snapshot, pools []config.Pool) error {
+	if err := applyPriceOverrides(snap.SpotPrices, o.SpotPrices); err != nil {
+		return err
+	}
+	if err := applyPriceOverrides(snap.OnDemandPrices, o.OnDemandPrices); err != nil {
+		return err
+	}
+	for _, o := range o.Probabilities {
+		if o.Probability < 0 || o.Probability > 4 {
+			return fmt.Errorf("probability override for %s has to be between 0 and 4", o.InstanceType)
+		}
+		matched := false
+		for k, data := range snap.Advisor {
+			parts := strings.SplitN(k, "--", 2)
+			regionMatch, err := globMatch(o.Region, parts[0])
+			if err != nil {
+				return err
+			}
+			itMatch, err := instanceTypeMatch(o.InstanceType, parts[1])
+			if err != nil {
+				return err
+			}
+			if regionMatch && itMatch {
+				data.Probability = o.Probability
+				snap.Advisor[k] = data
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("probability override %s in %q matches no spot advisor data", o.InstanceType, o.Region)
+		}
+	}
+	for _, o := range o.Nodes {
+		if err := applyNodesOverride(snap, pools, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyNodesOverride spreads the nodes evenly over the matching instance types and AZs,
+// the remainder goes to the first ones in alphabetical order. The nodes of an instance type
+// and AZ are added to the distribution of the pools with a node selector of the matching
+// ASGs too, as if they were launched by them.
+func applyNodesOverride(snap *Snapshot, pools []config.Pool, o NodesOverride) error {
+	if o.InstanceType == "" {
+		return fmt.Errorf("nodes override needs an instance type")
+	}
+	// the matching keys with the pools of their ASGs
+	matching := make(map[string]map[string]bool)
+	for asgName, asg := range snap.ASGs {
+		if asg.IsSpot != o.IsSpot {
+			continue
+		}
+		azMatch, err := globMatch(o.AvailabilityZone, asg.AvailabilityZone)
+		if err != nil {
+			return err
+		}
+		if !azMatch {
+			continue
+		}
+		for _, it := range asg.InstanceTypes {
+			itMatch, err := instanceTypeMatch(o.InstanceType, it)
+			if err != nil {
+				return err
+			}
+			if !itMatch {
+				continue
+			}
+			k := priceKey(it, asg.AvailabilityZone, o.IsSpot)
+			if matching[k] == nil {
+				matching[k] = make(map[string]bool)
+			}
+			if pool := config.PoolFor(pools, asgName, asg.Tags); pool != nil {
+				matching[k][pool.Name] = true
+			}
+		}
+	}
+	if len(matching) == 0 {
+		return fmt.Errorf("nodes override %s in %q matches no discovered ASG", o.InstanceType, o.AvailabilityZone)
+	}
+	keys := make([]string, 0, len(matching))
+	for k := range matching {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		count := o.Count / len(keys)
+		if remainder := o.Count % len(keys); i < abs(remainder) {
+			if remainder > 0 {
+				count++
+			} else {
+				count--
+			}
+		}
+		addNodes(snap.Nodes, k, count)
+		for pool := range matching[k] {
+			if counts, ok := snap.PoolNodes[pool]; ok {
+				addNodes(counts, k, count)
+			}
+		}
+	}
+	return nil
+}
+
+// addNodes adds the nodes to the distribution, never going below zero
+func addNodes(counts nodes.Counts, k string, count int) {
+	counts[k] += count
+	if counts[k] < 0 {
+		counts[k] = 0
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// WhatIfRequest describes an hypothetical scenario: Config is a partial ScorerConfiguration
+// merged over the current one, Hints are added to the current ones.
+type WhatIfRequest struct {
+	Config    json.RawMessage `json:"config,omitempty"`
+	Hints     HintsSpec       `json:"hints,omitempty"`
+	Overrides Overrides       `json:"overrides,omitempty"`
+}
+
+// WhatIf computes the priorities for an hypothetical scenario starting from
+// the current data, nothing is published. The external scorer decisions are applied
+// like for the published priorities, with the same failure policy.
+func (s *Scorer) WhatIf(req *WhatIfRequest) (*Result, error) {
+	snap := s.TakeSnapshot()
+	if len(snap.ASGs) == 0 {
+		return nil, ErrNoData
+	}
+
+	s.dataMu.RLock()
+	cfg, hints := s.config, s.hints
+	s.dataMu.RUnlock()
+
+	if len(req.Config) > 0 {
+		if err := config.Overlay(&cfg, req.Config); err != nil {
+			return nil, fmt.Errorf("invalid config: %v", err)
+		}
+		if err := config.Validate(cfg); err != nil {
+			return nil, fmt.Errorf("invalid config: %v", err)
+		}
+	}
+	extraHints, err := req.Hints.toHints()
+	if err != nil {
+		return nil, err
+	}
+	pools, err := cfg.ResolvePools()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	if err := req.Overrides.Apply(snap, pools); err != nil {
+		return nil, err
+	}
+	hints = mergeHints(hints, extraHints)
+	result := computeScores(snap, cfg, hints)
+	if s.externalScorer != nil && externalScoringEnabled(cfg) {
+		err := scoreExternally(s.externalScorer, extscorer.Request{Target: s.target, Cluster: s.cluster}, snap, cfg, hints, result)
+		if err != nil && s.externalScorer.FailClosed() {
+			return nil, &ExternalScorerError{Err: err}
+		} else if err != nil {
+			klog.Warningf("External scorer failed for what-if, using the built-in scores: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// Compute computes the priorities of a snapshot with the given configuration and hints,
+// it doesn't need a running Scorer so it can be used by one-shot commands.
+func Compute(snap *Snapshot, cfg config.ScorerConfiguration, hintsSpec HintsSpec) (*Result, error) {
+	hints, err := hintsSpec.toHints()
+	if err != nil {
+		return nil, err
+	}
+	return computeScores(snap, cfg, hints), nil
+}
+
+// WhatIfHandler serves WhatIf: POST a JSON WhatIfRequest to get back a JSON Result
+func (s *Scorer) WhatIfHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		req := &WhatIfRequest{}
+		body := http.MaxBytesReader(w, r.Body, maxWhatIfRequestSize)
+		if err := json.NewDecoder(body).Decode(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := s.WhatIf(req)
+		if _, ok := err.(*ExternalScorerError); ok {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		} else if err == ErrNoData {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, result)
+	})
+}
@@ -0,0 +1,152 @@
+package scorer
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNodesOverridePools(t *testing.T) {
+	_, pools := poolsConfig(t)
+	snap := newPoolsSnapshot()
+	o := Overrides{Nodes: []NodesOverride{{InstanceType: "m5", IsSpot: true, Count: 4}}}
+	if err := o.Apply(snap, pools); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	k := priceKey("m5.large", "eu-west-1a", true)
+	// the nodes go to the pool of the batch ASG, not to the pool w/o matching ASGs
+	if snap.Nodes[k] != 9 || snap.PoolNodes["batch"][k] != 6 || snap.PoolNodes["other"][k] != 1 {
+		t.Errorf("nodes %d, batch nodes %d, other nodes %d, want 9, 6, 1", snap.Nodes[k], snap.PoolNodes["batch"][k], snap.PoolNodes["other"][k])
+	}
+
+	o = Overrides{Nodes: []NodesOverride{{InstanceType: "m5.large", IsSpot: true, Count: -20}}}
+	if err := o.Apply(snap, pools); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if snap.Nodes[k] != 0 || snap.PoolNodes["batch"][k] != 0 {
+		t.Errorf("nodes %d, batch nodes %d, want 0, 0", snap.Nodes[k], snap.PoolNodes["batch"][k])
+	}
+}
+
+func TestOverridesApply(t *testing.T) {
+	newSnapshot := func() *Snapshot {
+		snap := NewSnapshot(time.Now())
+		for _, it := range []string{"c5.large", "c5n.large", "m5.xlarge"} {
+			for _, az := range []string{"eu-west-1a", "eu-west-1b"} {
+				snap.ASGs[it+"-"+az] = ASGSnapshot{InstanceTypes: []string{it}, AvailabilityZone: az, IsSpot: true}
+				snap.SpotPrices[priceKey(it, az, true)] = 0.05
+				snap.OnDemandPrices[priceKey(it, az, false)] = 0.1
+			}
+			snap.Advisor[advisorKey("eu-west-1", it)] = AdvisorData{Probability: 1}
+		}
+		return snap
+	}
+	tests := []struct {
+		name          string
+		overrides     Overrides
+		spotPrices    map[string]float64
+		probabilities map[string]int
+		nodes         map[string]int
+		wantErr       string
+	}{
+		{
+			name:      "exact price",
+			overrides: Overrides{SpotPrices: []PriceOverride{{InstanceType: "m5.xlarge", AvailabilityZone: "eu-west-1b", Price: 0.2}}},
+			spotPrices: map[string]float64{
+				priceKey("m5.xlarge", "eu-west-1a", true): 0.05, priceKey("m5.xlarge", "eu-west-1b", true): 0.2,
+			},
+		},
+		{
+			name:      "family price factor",
+			overrides: Overrides{SpotPrices: []PriceOverride{{InstanceType: "c5", Factor: 2}}},
+			spotPrices: map[string]float64{
+				priceKey("c5.large", "eu-west-1a", true): 0.1, priceKey("c5.large", "eu-west-1b", true): 0.1,
+				priceKey("c5n.large", "eu-west-1a", true): 0.05,
+			},
+		},
+		{
+			name:      "glob price and factor",
+			overrides: Overrides{SpotPrices: []PriceOverride{{InstanceType: "c5*", AvailabilityZone: "*-1a", Price: 0.1, Factor: 3}}},
+			spotPrices: map[string]float64{
+				priceKey("c5.large", "eu-west-1a", true): 0.3, priceKey("c5n.large", "eu-west-1a", true): 0.3,
+				priceKey("c5.large", "eu-west-1b", true): 0.05,
+			},
+		},
+		{
+			name:          "glob probability",
+			overrides:     Overrides{Probabilities: []ProbabilityOverride{{InstanceType: "c5.*", Region: "eu-west-1", Probability: 4}}},
+			probabilities: map[string]int{"c5.large": 4, "c5n.large": 1, "m5.xlarge": 1},
+		},
+		{
+			name:      "nodes spread",
+			overrides: Overrides{Nodes: []NodesOverride{{InstanceType: "c5", IsSpot: true, Count: 3}}},
+			nodes:     map[string]int{priceKey("c5.large", "eu-west-1a", true): 2, priceKey("c5.large", "eu-west-1b", true): 1},
+		},
+		{
+			name:      "negative price",
+			overrides: Overrides{OnDemandPrices: []PriceOverride{{InstanceType: "c5.large", Price: -1}}},
+			wantErr:   "can't be negative",
+		},
+		{
+			name:      "price matching nothing",
+			overrides: Overrides{SpotPrices: []PriceOverride{{InstanceType: "c6.large"}}},
+			wantErr:   "matches no known price",
+		},
+		{
+			name:      "invalid pattern",
+			overrides: Overrides{SpotPrices: []PriceOverride{{InstanceType: "c5.[", Factor: 2}}},
+			wantErr:   "syntax error in pattern",
+		},
+		{
+			name:      "probability out of range",
+			overrides: Overrides{Probabilities: []ProbabilityOverride{{InstanceType: "c5.large", Probability: 5}}},
+			wantErr:   "between 0 and 4",
+		},
+		{
+			name:      "probability matching nothing",
+			overrides: Overrides{Probabilities: []ProbabilityOverride{{InstanceType: "c5.large", Region: "us-east-1", Probability: 2}}},
+			wantErr:   "matches no spot advisor data",
+		},
+		{
+			name:      "nodes w/o instance type",
+			overrides: Overrides{Nodes: []NodesOverride{{IsSpot: true, Count: 3}}},
+			wantErr:   "needs an instance type",
+		},
+		{
+			name:      "on-demand nodes matching nothing",
+			overrides: Overrides{Nodes: []NodesOverride{{InstanceType: "c5", Count: 3}}},
+			wantErr:   "matches no discovered ASG",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap := newSnapshot()
+			err := tt.overrides.Apply(snap, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Apply() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			for k, price := range tt.spotPrices {
+				if math.Abs(snap.SpotPrices[k]-price) > 1e-9 {
+					t.Errorf("spot price of %s = %v, want %v", k, snap.SpotPrices[k], price)
+				}
+			}
+			for it, probability := range tt.probabilities {
+				if got := snap.Advisor[advisorKey("eu-west-1", it)].Probability; got != probability {
+					t.Errorf("probability of %s = %d, want %d", it, got, probability)
+				}
+			}
+			for k, count := range tt.nodes {
+				if snap.Nodes[k] != count {
+					t.Errorf("nodes of %s = %d, want %d", k, snap.Nodes[k], count)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"k8s.io/klog"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Server is the embedded HTTP server, handlers have to be registered before Start
+type Server struct {
+	mux *http.ServeMux
+	srv *http.Server
+}
+
+func NewServer(address string) *Server {
+	mux := http.NewServeMux()
+	return &Server{
+		mux: mux,
+		srv: &http.Server{Addr: address, Handler: mux},
+	}
+}
+
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) Start(stopCh <-chan struct{}) {
+	go func() {
+		klog.V(1).Infof("HTTP server listening on %s", s.srv.Addr)
+		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("HTTP server failed: %v", err)
+		}
+	}()
+	go func() {
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := s.srv.Shutdown(ctx); err != nil {
+			klog.Errorf("HTTP server shutdown failed: %v", err)
+		}
+	}()
+}
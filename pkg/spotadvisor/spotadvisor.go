@@ -160,3 +160,27 @@ func (sad *SpotAdvisor) GetRamGbFor(iType string) int {
 	}
 	return -1
 }
+
+// GetMemoryFor returns the memory in GiB of the instance type, -1 if unknown
+func (sad *SpotAdvisor) GetMemoryFor(iType string) float64 {
+	if res, ok := sad.getInstanceTypeFor(iType); ok {
+		return res.Ram_gb
+	}
+	return -1
+}
+
+// GetRangeLabelFor returns the label of the interruption frequency range (like "<5%"), empty if unknown
+func (sad *SpotAdvisor) GetRangeLabelFor(region string, osType string, iType string) string {
+	if sad == nil {
+		return ""
+	}
+	r := sad.GetProbabilityFor(region, osType, iType)
+	sad.DataManager.RLock()
+	defer sad.DataManager.RUnlock()
+	for _, rng := range sad.Ranges {
+		if rng.Index == r {
+			return rng.Label
+		}
+	}
+	return ""
+}
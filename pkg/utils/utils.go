@@ -11,32 +11,42 @@ import (
 )
 
 func GetClientset(kubeconfig string, overrides *clientcmd.ConfigOverrides) (clientset.Interface, error) {
+	config, err := GetRestConfig(kubeconfig, overrides)
+	if err != nil {
+		return nil, err
+	}
+	// create the clientset
+	clientset, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return clientset, nil
+}
+
+// GetRestConfig returns the in-cluster configuration or the one from the kubeconfig, when
+// a kubeconfig or a context is given.
+func GetRestConfig(kubeconfig string, overrides *clientcmd.ConfigOverrides) (*rest.Config, error) {
 	var config *rest.Config
 	var err error
-	if kubeconfig == "" {
+	// a context can be selected only from a kubeconfig
+	if kubeconfig == "" && (overrides == nil || overrides.CurrentContext == "") {
 		config, err = rest.InClusterConfig()
 		if err == rest.ErrNotInCluster {
 			err = nil
-			kubeconfig = clientcmd.RecommendedHomeFile
 		}
 	}
 
 	if err == nil && config == nil {
+		if kubeconfig == "" {
+			kubeconfig = clientcmd.RecommendedHomeFile
+		}
 		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 			&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
 			overrides,
 		).ClientConfig()
 	}
 
-	if err != nil {
-		return nil, err
-	}
-	// create the clientset
-	clientset, err := clientset.NewForConfig(config)
-	if err != nil {
-		return nil, err
-	}
-	return clientset, nil
+	return config, err
 }
 
 const (